@@ -0,0 +1,92 @@
+package osc
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Address builds an OSC address from its parts, checking each one, so
+// addresses don't have to be put together with fmt.Sprintf. For example
+//
+//	Addr("mixer").Child("ch", 3).Child("level")
+//
+// is "/mixer/ch/3/level". Addresses are values: each method returns a new
+// Address, leaving the one it was called on unchanged.
+type Address struct {
+	s   string
+	err error
+}
+
+// Addr returns the address with the provided parts, see Address.Child.
+func Addr(parts ...any) Address {
+	return Address{}.Child(parts...)
+}
+
+// Child returns the address with each of parts appended as a separate part.
+// Strings are used as they are, and other values are formatted with fmt.Sprint,
+// so integers are written in decimal. A part that is empty or contains a
+// character not allowed in an OSC method name makes the address invalid.
+func (a Address) Child(parts ...any) Address {
+	var b strings.Builder
+	b.WriteString(a.s)
+	for _, p := range parts {
+		var s string
+		switch p := p.(type) {
+		case string:
+			s = p
+		case int:
+			s = strconv.Itoa(p)
+		default:
+			s = fmt.Sprint(p)
+		}
+		if err := checkPart(s); err != nil && a.err == nil {
+			a.err = err
+		}
+		b.WriteByte('/')
+		b.WriteString(s)
+	}
+	a.s = b.String()
+	return a
+}
+
+// Param returns the address with a part made of name followed by the decimal
+// n, for devices that number their parameters like "/track3/volume".
+func (a Address) Param(name string, n int) Address {
+	return a.Child(name + strconv.Itoa(n))
+}
+
+// Join returns the address with the parts of o appended.
+func (a Address) Join(o Address) Address {
+	if a.err == nil {
+		a.err = o.err
+	}
+	a.s += o.s
+	return a
+}
+
+// Validate returns the first problem with any part of the address, wrapping
+// ErrBadAddress, or nil if it's valid. An address with no parts isn't valid.
+func (a Address) Validate() error {
+	if a.s == "" {
+		return fmt.Errorf("%w: empty address", ErrBadAddress)
+	}
+	return a.err
+}
+
+// String returns the address, even if it isn't valid.
+func (a Address) String() string {
+	return a.s
+}
+
+// checkPart checks s can be a part of an address. The characters are those the
+// OSC spec doesn't allow in method names, plus the zero byte.
+func checkPart(s string) error {
+	if s == "" {
+		return fmt.Errorf("%w: empty part", ErrBadAddress)
+	}
+	if i := strings.IndexAny(s, " #*,/?[]{}\x00"); i >= 0 {
+		return fmt.Errorf("%w: part %q contains %q", ErrBadAddress, s, s[i])
+	}
+	return nil
+}
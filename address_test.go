@@ -0,0 +1,38 @@
+package osc
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestAddress(t *testing.T) {
+	mixer := Addr("mixer")
+	for _, c := range []struct {
+		addr Address
+		want string
+		err  bool
+	}{
+		{mixer, "/mixer", false},
+		{mixer.Child("ch", 3).Child("level"), "/mixer/ch/3/level", false},
+		{mixer.Param("track", 12).Child("mute"), "/mixer/track12/mute", false},
+		{Addr("a").Join(Addr("b", int32(-1))), "/a/b/-1", false},
+		{Addr(), "", true},
+		{mixer.Child(""), "/mixer/", true},
+		{mixer.Child("ch/1"), "/mixer/ch/1", true},
+		{mixer.Child("*"), "/mixer/*", true},
+		{mixer.Child("a b"), "/mixer/a b", true},
+		{Addr("a").Join(mixer.Child("{x,y}")), "/a/mixer/{x,y}", true},
+	} {
+		err := c.addr.Validate()
+		if got := c.addr.String(); got != c.want || (err != nil) != c.err {
+			t.Errorf("Address %q, Validate() = %v, want: %q, error: %t", got, err, c.want, c.err)
+		}
+		if err != nil && !errors.Is(err, ErrBadAddress) {
+			t.Errorf("Address(%q).Validate() = %v, want: %v", c.addr, err, ErrBadAddress)
+		}
+	}
+	// Building from an address doesn't change it.
+	if mixer.String() != "/mixer" {
+		t.Errorf("mixer = %q after building children, want: /mixer", mixer)
+	}
+}
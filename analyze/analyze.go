@@ -0,0 +1,279 @@
+// Package analyze checks OSC traffic for violations of the spec and risky
+// behaviour, such as bad padding or oversized packets, and reports them per
+// peer. It's meant for debugging devices whose messages other implementations
+// reject or misread.
+package analyze
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"slices"
+	"sync"
+)
+
+// Issue is a kind of problem found in a packet.
+type Issue string
+
+const (
+	// Malformed means the packet couldn't be read as a message at all.
+	Malformed Issue = "malformed"
+	// Unaligned means the packet's length isn't a multiple of four.
+	Unaligned Issue = "length not a multiple of 4"
+	// Oversized means the packet is larger than the Analyzer's MaxSize, so
+	// would likely be fragmented or dropped on a real network.
+	Oversized Issue = "oversized packet"
+	// BadAddress means the address doesn't start with a "/".
+	BadAddress Issue = "address doesn't start with /"
+	// MissingTypeTags means there is no type tag string, as sent by some old
+	// implementations.
+	MissingTypeTags Issue = "missing type tags"
+	// UnknownTypeTag means a type tag isn't one of the standard ones, so the
+	// rest of the message couldn't be checked.
+	UnknownTypeTag Issue = "unknown type tag"
+	// BadPadding means a string or blob is followed by too little padding,
+	// or padding that isn't zeros.
+	BadPadding Issue = "bad padding"
+	// NonASCII means the address or a string contains bytes outside of
+	// printable ASCII.
+	NonASCII Issue = "non-ASCII string"
+	// TrailingBytes means there are bytes left over after the last
+	// argument.
+	TrailingBytes Issue = "trailing bytes"
+	// Bundle means the packet is a bundle, which isn't checked.
+	Bundle Issue = "bundle (not checked)"
+)
+
+// maxPeers limits how many peers an Analyzer keeps separate analyses for.
+// Packets from any more are counted together under OtherPeers.
+const maxPeers = 1024
+
+// OtherPeers is the address of the Peer counting packets from peers beyond
+// the first thousand or so.
+const OtherPeers = "<other>"
+
+// DefaultMaxSize is the largest packet that fits in a typical Ethernet frame
+// without fragmenting.
+const DefaultMaxSize = 1472
+
+// Analyzer collects issues found in packets from each peer. It's safe for
+// concurrent use.
+type Analyzer struct {
+	// MaxSize is the size above which packets are reported as Oversized.
+	MaxSize int
+
+	mu    sync.Mutex
+	peers map[string]*Peer
+}
+
+// Peer is the analysis of the traffic from a single peer.
+type Peer struct {
+	Addr    string
+	Packets int
+	// Issues counts packets with each issue. A packet with the same issue
+	// more than once is only counted once.
+	Issues map[Issue]int
+	// Examples holds the address of the first message found with each
+	// issue, if it could be read.
+	Examples map[Issue]string
+}
+
+// New returns an Analyzer with MaxSize set to DefaultMaxSize.
+func New() *Analyzer {
+	return &Analyzer{
+		MaxSize: DefaultMaxSize,
+		peers:   make(map[string]*Peer),
+	}
+}
+
+// Observe checks a packet received from addr, returning the issues found.
+func (a *Analyzer) Observe(addr net.Addr, packet []byte) []Issue {
+	address, issues := Check(packet)
+	if a.MaxSize > 0 && len(packet) > a.MaxSize {
+		issues = append(issues, Oversized)
+	}
+	key := "<unknown>"
+	if addr != nil {
+		key = addr.String()
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if _, ok := a.peers[key]; !ok && len(a.peers) >= maxPeers {
+		key = OtherPeers
+	}
+	p, ok := a.peers[key]
+	if !ok {
+		p = &Peer{Addr: key, Issues: make(map[Issue]int), Examples: make(map[Issue]string)}
+		a.peers[key] = p
+	}
+	p.Packets++
+	for _, i := range issues {
+		p.Issues[i]++
+		if _, ok := p.Examples[i]; !ok {
+			p.Examples[i] = address
+		}
+	}
+	return issues
+}
+
+// Peers returns a copy of the analysis for each peer, sorted by address.
+func (a *Analyzer) Peers() []Peer {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var peers []Peer
+	for _, p := range a.peers {
+		c := *p
+		c.Issues = make(map[Issue]int, len(p.Issues))
+		c.Examples = make(map[Issue]string, len(p.Examples))
+		for i, n := range p.Issues {
+			c.Issues[i] = n
+			c.Examples[i] = p.Examples[i]
+		}
+		peers = append(peers, c)
+	}
+	slices.SortFunc(peers, func(a, b Peer) int {
+		switch {
+		case a.Addr < b.Addr:
+			return -1
+		case a.Addr > b.Addr:
+			return 1
+		}
+		return 0
+	})
+	return peers
+}
+
+// Report writes a human readable report of the issues found from each peer.
+func (a *Analyzer) Report(w io.Writer) error {
+	for _, p := range a.Peers() {
+		if _, err := fmt.Fprintf(w, "%s: %d packets\n", p.Addr, p.Packets); err != nil {
+			return err
+		}
+		issues := make([]Issue, 0, len(p.Issues))
+		for i := range p.Issues {
+			issues = append(issues, i)
+		}
+		slices.Sort(issues)
+		for _, i := range issues {
+			if _, err := fmt.Fprintf(w, "\t%s: %d packets (e.g. %q)\n", i, p.Issues[i], p.Examples[i]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Check checks a single packet, returning the message's address, if it could
+// be read, and the issues found.
+func Check(packet []byte) (string, []Issue) {
+	var issues []Issue
+	add := func(i Issue) {
+		if !slices.Contains(issues, i) {
+			issues = append(issues, i)
+		}
+	}
+	if len(packet)%4 != 0 {
+		add(Unaligned)
+	}
+	address, rest, ok := checkString(packet, add)
+	if !ok {
+		add(Malformed)
+		return "", issues
+	}
+	if address == "#bundle" {
+		return address, append(issues, Bundle)
+	}
+	if len(address) == 0 || address[0] != '/' {
+		add(BadAddress)
+	}
+	if len(rest) == 0 || rest[0] != ',' {
+		add(MissingTypeTags)
+		return address, issues
+	}
+	tags, rest, ok := checkString(rest, add)
+	if !ok {
+		add(Malformed)
+		return address, issues
+	}
+	for _, t := range tags[1:] {
+		var n int
+		switch t {
+		case 'i', 'f', 'c', 'r', 'm':
+			n = 4
+		case 'h', 'd', 't':
+			n = 8
+		case 'T', 'F', 'N', 'I':
+		case 's', 'S':
+			if _, rest, ok = checkString(rest, add); !ok {
+				add(Malformed)
+				return address, issues
+			}
+			continue
+		case 'b':
+			if rest, ok = checkBlob(rest, add); !ok {
+				add(Malformed)
+				return address, issues
+			}
+			continue
+		default:
+			add(UnknownTypeTag)
+			return address, issues
+		}
+		if len(rest) < n {
+			add(Malformed)
+			return address, issues
+		}
+		rest = rest[n:]
+	}
+	if len(rest) > 0 {
+		add(TrailingBytes)
+	}
+	return address, issues
+}
+
+// checkString reads a string from the start of b, reporting problems with it
+// to add. It only fails if there's no terminating zero.
+func checkString(b []byte, add func(Issue)) (string, []byte, bool) {
+	n := slices.Index(b, 0)
+	if n < 0 {
+		return "", nil, false
+	}
+	s := b[:n]
+	for _, c := range s {
+		if c < 0x20 || c > 0x7e {
+			add(NonASCII)
+			break
+		}
+	}
+	return string(s), checkPadding(b[n:], 4-n%4, add), true
+}
+
+// checkBlob reads a blob from the start of b, reporting problems with it to
+// add.
+func checkBlob(b []byte, add func(Issue)) ([]byte, bool) {
+	if len(b) < 4 {
+		return nil, false
+	}
+	n := int(int32(binary.BigEndian.Uint32(b)))
+	b = b[4:]
+	if n < 0 || n > len(b) {
+		return nil, false
+	}
+	return checkPadding(b[n:], (4-n%4)%4, add), true
+}
+
+// checkPadding checks b starts with n zero bytes, and returns the rest.
+func checkPadding(b []byte, n int, add func(Issue)) []byte {
+	if len(b) < n {
+		add(BadPadding)
+		n = len(b)
+	}
+	for _, c := range b[:n] {
+		if c != 0 {
+			add(BadPadding)
+			break
+		}
+	}
+	return b[n:]
+}
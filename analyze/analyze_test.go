@@ -0,0 +1,93 @@
+package analyze
+
+import (
+	"net"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/pfcm/osc"
+)
+
+func TestCheck(t *testing.T) {
+	good := osc.Message{
+		Pattern: "/good",
+		Arguments: []osc.Argument{
+			osc.AsInt32(1),
+			osc.AsString("abc"),
+			&osc.Blob{1, 2, 3, 4, 5},
+			osc.True{},
+		},
+	}
+	for _, c := range []struct {
+		name   string
+		packet []byte
+		want   []Issue
+	}{
+		{"valid", good.Append(nil), nil},
+		{"no type tags", []byte("/old\x00\x00\x00\x00\x00\x00\x00\x01"), []Issue{MissingTypeTags}},
+		// Reading continues after the expected padding, which here is in
+		// the type tags.
+		{"short padding", []byte("/abc\x00,i\x00\x00\x00\x00\x01"), []Issue{BadPadding, MissingTypeTags}},
+		{"nonzero padding", []byte("/ab\x00,i\x00x\x00\x00\x00\x01"), []Issue{BadPadding}},
+		{"unaligned", []byte("/a\x00\x00,\x00\x00\x00\x00"), []Issue{Unaligned, TrailingBytes}},
+		{"non-ascii", osc.Message{Pattern: "/caf\xc3\xa9"}.Append(nil), []Issue{NonASCII}},
+		{"bad address", osc.Message{Pattern: "a"}.Append(nil), []Issue{BadAddress}},
+		{"unterminated", []byte("/abcd"), []Issue{Unaligned, Malformed}},
+		{"short argument", []byte("/a\x00\x00,i\x00\x00\x00\x01"), []Issue{Unaligned, Malformed}},
+		{"unknown tag", []byte("/a\x00\x00,q\x00\x00"), []Issue{UnknownTypeTag}},
+		{"bundle", []byte("#bundle\x00"), []Issue{Bundle}},
+	} {
+		if _, got := Check(c.packet); !slices.Equal(got, c.want) {
+			t.Errorf("%s: Check(%q) = %v, want: %v", c.name, c.packet, got, c.want)
+		}
+	}
+}
+
+func TestAnalyzer(t *testing.T) {
+	a := New()
+	a.MaxSize = 64
+	x := &net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 1}
+	y := &net.UDPAddr{IP: net.IPv4(10, 0, 0, 2), Port: 1}
+	big := osc.Blob(make([]byte, 100))
+	a.Observe(x, osc.Message{Pattern: "/ok"}.Append(nil))
+	a.Observe(x, osc.Message{Pattern: "/big", Arguments: []osc.Argument{&big}}.Append(nil))
+	a.Observe(y, []byte("/old\x00\x00\x00\x00"))
+
+	peers := a.Peers()
+	if len(peers) != 2 {
+		t.Fatalf("got %d peers, want: 2", len(peers))
+	}
+	if p := peers[0]; p.Packets != 2 || p.Issues[Oversized] != 1 || p.Examples[Oversized] != "/big" {
+		t.Errorf("peer %v = %+v, want 2 packets with 1 oversized", x, p)
+	}
+	if p := peers[1]; p.Packets != 1 || p.Issues[MissingTypeTags] != 1 {
+		t.Errorf("peer %v = %+v, want 1 packet missing type tags", y, p)
+	}
+
+	var report strings.Builder
+	if err := a.Report(&report); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"10.0.0.1:1: 2 packets", `oversized packet: 1 packets (e.g. "/big")`, "missing type tags"} {
+		if !strings.Contains(report.String(), want) {
+			t.Errorf("report missing %q:\n%s", want, report.String())
+		}
+	}
+}
+
+func TestAnalyzerPeerLimit(t *testing.T) {
+	a := New()
+	packet := osc.Message{Pattern: "/ok"}.Append(nil)
+	for i := range maxPeers + 10 {
+		a.Observe(&net.UDPAddr{IP: net.IPv4(10, 0, byte(i>>8), byte(i)), Port: 1}, packet)
+	}
+	peers := a.Peers()
+	if len(peers) != maxPeers+1 {
+		t.Errorf("%d peers seen, got analyses for %d, want: %d", maxPeers+10, len(peers), maxPeers+1)
+	}
+	i := slices.IndexFunc(peers, func(p Peer) bool { return p.Addr == OtherPeers })
+	if i < 0 || peers[i].Packets != 10 {
+		t.Errorf("peers beyond the limit not counted under %q: %+v", OtherPeers, peers)
+	}
+}
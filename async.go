@@ -0,0 +1,158 @@
+//go:build !tinygo
+
+package osc
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"sync/atomic"
+	"time"
+)
+
+// ErrQueueFull is returned when a message can't be queued because the queue is
+// already full.
+var ErrQueueFull = errors.New("send queue full")
+
+// AsyncSender sends messages to a single destination from a dedicated
+// goroutine, so that callers (e.g. real-time audio threads) never wait on the
+// network. Messages are encoded by the caller into a fixed size ring buffer,
+// which doesn't take any locks and, once each slot has been used once, doesn't
+// allocate. Run is only woken through a channel, which does lock, when it may
+// be idle, not for every message. Send may be called concurrently, but Run must
+// only be called once.
+type AsyncSender struct {
+	conn net.PacketConn
+	addr net.Addr
+
+	slots []asyncSlot
+	mask  uint64
+	head  atomic.Uint64 // next slot to write
+	tail  uint64        // next slot to read, only touched by Run
+
+	// wake is used to tell Run there might be something to send. pending is
+	// set once it has been, until Run next looks at the queue, so senders
+	// can skip the channel while Run is busy anyway.
+	wake    chan struct{}
+	pending atomic.Bool
+	dropped atomic.Uint64
+	expired atomic.Uint64
+}
+
+type asyncSlot struct {
+	// seq is the position in the queue this slot is ready for: equal to the
+	// position when it's ready to be written and the position + 1 when it's
+	// ready to be read.
+	seq atomic.Uint64
+	buf []byte
+	// expires is when the message is no longer worth sending, or zero if
+	// it never expires.
+	expires time.Time
+}
+
+// NewAsyncSender returns an AsyncSender that writes to addr using conn. The
+// queue holds at least size messages.
+func NewAsyncSender(conn net.PacketConn, addr net.Addr, size int) *AsyncSender {
+	n := 1
+	for n < size {
+		n <<= 1
+	}
+	a := &AsyncSender{
+		conn:  conn,
+		addr:  addr,
+		slots: make([]asyncSlot, n),
+		mask:  uint64(n - 1),
+		wake:  make(chan struct{}, 1),
+	}
+	for i := range a.slots {
+		a.slots[i].seq.Store(uint64(i))
+	}
+	return a
+}
+
+// Send queues a message to be sent. It never blocks, returning ErrQueueFull if
+// there is no room in the queue.
+func (a *AsyncSender) Send(msg *Message) error {
+	return a.SendExpiring(msg, time.Time{})
+}
+
+// SendExpiring is like Send, but the message is dropped rather than sent if it's
+// still queued at the expiry time. This stops stale control values being sent
+// late after the link stalls, so devices don't jump through outdated values
+// when it recovers. A zero expiry never expires.
+func (a *AsyncSender) SendExpiring(msg *Message, expires time.Time) error {
+	pos := a.head.Load()
+	var s *asyncSlot
+	for {
+		s = &a.slots[pos&a.mask]
+		seq := s.seq.Load()
+		if seq < pos {
+			// The slot still holds an unsent message from the last
+			// time around the ring.
+			a.dropped.Add(1)
+			return ErrQueueFull
+		}
+		if seq == pos && a.head.CompareAndSwap(pos, pos+1) {
+			break
+		}
+		// Someone else claimed this position.
+		pos = a.head.Load()
+	}
+	s.buf = msg.Append(s.buf[:0])
+	s.expires = expires
+	s.seq.Store(pos + 1)
+	if !a.pending.Load() && !a.pending.Swap(true) {
+		select {
+		case a.wake <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// Dropped returns the number of messages that have been dropped because the
+// queue was full.
+func (a *AsyncSender) Dropped() uint64 {
+	return a.dropped.Load()
+}
+
+// Expired returns the number of messages that have been dropped because they
+// expired before they could be sent.
+func (a *AsyncSender) Expired() uint64 {
+	return a.expired.Load()
+}
+
+// Run sends queued messages until the context is cancelled. Errors writing
+// individual messages are logged, they do not stop the loop.
+func (a *AsyncSender) Run(ctx context.Context) error {
+	for {
+		// Clear pending before looking, so a message queued after the
+		// last look always wakes us.
+		a.pending.Store(false)
+		for a.next() {
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-a.wake:
+		}
+	}
+}
+
+// next sends the message at the tail of the queue, reporting whether there
+// was one.
+func (a *AsyncSender) next() bool {
+	s := &a.slots[a.tail&a.mask]
+	if s.seq.Load() != a.tail+1 {
+		return false
+	}
+	if !s.expires.IsZero() && time.Now().After(s.expires) {
+		a.expired.Add(1)
+	} else if _, err := a.conn.WriteTo(s.buf, a.addr); err != nil {
+		log.Printf("Error sending to %v: %v", a.addr, err)
+	}
+	s.seq.Store(a.tail + a.mask + 1)
+	a.tail++
+	return true
+}
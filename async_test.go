@@ -0,0 +1,114 @@
+//go:build !tinygo
+
+package osc
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestAsyncSender(t *testing.T) {
+	recv, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer recv.Close()
+	send, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer send.Close()
+
+	const n = 16
+	a := NewAsyncSender(send, recv.LocalAddr(), n)
+	for i := range n {
+		if err := a.Send(&Message{Pattern: "/async", Arguments: []Argument{AsInt32(i)}}); err != nil {
+			t.Fatalf("Send(%d): %v", i, err)
+		}
+	}
+	if err := a.Send(&Message{Pattern: "/full"}); err != ErrQueueFull {
+		t.Errorf("Send to full queue: got %v, want %v", err, ErrQueueFull)
+	}
+	if got := a.Dropped(); got != 1 {
+		t.Errorf("Dropped() = %d, want 1", got)
+	}
+	// Only the first message woke Run.
+	if !a.pending.Load() || len(a.wake) != 1 {
+		t.Errorf("after %d sends, pending = %v with %d wakeups queued, want: true with 1", n, a.pending.Load(), len(a.wake))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go a.Run(ctx)
+
+	recv.SetReadDeadline(time.Now().Add(5 * time.Second))
+	buf := make([]byte, 1024)
+	for i := range n {
+		n, _, err := recv.ReadFrom(buf)
+		if err != nil {
+			t.Fatalf("ReadFrom: %v", err)
+		}
+		msg, err := ParseMessage(buf[:n])
+		if err != nil {
+			t.Fatalf("ParseMessage: %v", err)
+		}
+		if got := int(*msg.Arguments[0].(*Int32)); got != i {
+			t.Errorf("message %d: got argument %d", i, got)
+		}
+	}
+
+	// Once the queue has drained, the next message wakes Run again.
+	if err := a.Send(&Message{Pattern: "/again"}); err != nil {
+		t.Fatalf("Send after draining: %v", err)
+	}
+	size, _, err := recv.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("ReadFrom after draining: %v", err)
+	}
+	if msg, err := ParseMessage(buf[:size]); err != nil || msg.Pattern != "/again" {
+		t.Errorf("received %v, %v after draining, want: /again", msg, err)
+	}
+}
+
+func TestAsyncSenderExpiry(t *testing.T) {
+	recv, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer recv.Close()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	a := NewAsyncSender(conn, recv.LocalAddr(), 8)
+	// Queue everything before running, as if the link had stalled.
+	a.SendExpiring(&Message{Pattern: "/stale"}, time.Now().Add(-time.Second))
+	a.SendExpiring(&Message{Pattern: "/fresh"}, time.Now().Add(time.Hour))
+	a.Send(&Message{Pattern: "/forever"})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go a.Run(ctx)
+
+	buf := make([]byte, 64)
+	for _, want := range []string{"/fresh", "/forever"} {
+		recv.SetReadDeadline(time.Now().Add(5 * time.Second))
+		n, _, err := recv.ReadFrom(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		msg, err := ParseMessage(buf[:n])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if msg.Pattern != want {
+			t.Errorf("received %v, want: %s", msg, want)
+		}
+	}
+	if got := a.Expired(); got != 1 {
+		t.Errorf("Expired() = %d, want: 1", got)
+	}
+}
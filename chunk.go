@@ -0,0 +1,37 @@
+package osc
+
+import "hash/crc32"
+
+// Chunked transfers send a payload too large for a single packet as a series of
+// messages to the same address. Each chunk is a message with the arguments
+// (transfer id, offset, data), and the transfer is finished by a message with
+// the arguments (transfer id, total size, CRC-32 checksum of the payload).
+const (
+	// ChunkTypeTag is the type tag of a message carrying a chunk.
+	ChunkTypeTag = "iib"
+	// ChunkEndTypeTag is the type tag of the message finishing a transfer.
+	ChunkEndTypeTag = "iii"
+)
+
+// Chunks splits data into messages to the given address, each carrying at most
+// size bytes of it, followed by the message finishing the transfer. The id
+// should be unique amongst concurrent transfers to the same address.
+func Chunks(address string, id int32, data []byte, size int) []*Message {
+	size = max(size, 1)
+	msgs := make([]*Message, 0, len(data)/size+2)
+	for off := 0; off < len(data); off += size {
+		chunk := Blob(data[off:min(off+size, len(data))])
+		msgs = append(msgs, &Message{
+			Pattern:   address,
+			Arguments: []Argument{AsInt32(id), AsInt32(off), &chunk},
+		})
+	}
+	return append(msgs, &Message{
+		Pattern: address,
+		Arguments: []Argument{
+			AsInt32(id),
+			AsInt32(len(data)),
+			AsInt32(crc32.ChecksumIEEE(data)),
+		},
+	})
+}
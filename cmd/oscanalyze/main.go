@@ -0,0 +1,79 @@
+// oscanalyze listens for OSC packets and reports violations of the spec and
+// risky behaviour from each peer that sends to it. Point a device at it to find
+// out why other software won't talk to it.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/pfcm/osc/analyze"
+)
+
+var (
+	listenAddrFlag = flag.String("listen_addr", "127.0.0.1:9000", "`host:port`: the address to listen on.")
+	intervalFlag   = flag.Duration("interval", 10*time.Second, "how often to print a report, or 0 to only print one on exit")
+	maxSizeFlag    = flag.Int("max_size", analyze.DefaultMaxSize, "packets larger than this many `bytes` are reported as oversized")
+	verboseFlag    = flag.Bool("verbose", false, "log every packet with issues as it arrives")
+)
+
+func main() {
+	flag.Parse()
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+	if err := run(ctx); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run(ctx context.Context) error {
+	conn, err := net.ListenPacket("udp", *listenAddrFlag)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	log.Printf("Analyzing packets sent to %v", conn.LocalAddr())
+	go func() {
+		<-ctx.Done()
+		conn.SetReadDeadline(time.Now())
+	}()
+
+	a := analyze.New()
+	a.MaxSize = *maxSizeFlag
+	if *intervalFlag > 0 {
+		t := time.NewTicker(*intervalFlag)
+		defer t.Stop()
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-t.C:
+					a.Report(os.Stdout)
+				}
+			}
+		}()
+	}
+
+	buf := make([]byte, 1<<16) // ~max UDP packet size.
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if n > 0 {
+			if issues := a.Observe(addr, buf[:n]); *verboseFlag && len(issues) > 0 {
+				log.Printf("%v: %v: %q", addr, issues, buf[:n])
+			}
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			return err
+		}
+	}
+	return a.Report(os.Stdout)
+}
@@ -0,0 +1,220 @@
+// oscgen generates typed Go bindings for a device from a namespace
+// description, in the JSON format read by devicesim.ParseDescription. For each
+// parameter it generates a client method to set it and one to query it, and a
+// method on a handler interface for servers to implement, so programs don't
+// have to spell out addresses and type tags by hand. It's meant to be run by
+// go generate:
+//
+//	//go:generate go run github.com/pfcm/osc/cmd/oscgen -in desk.json -out desk.go -package desk
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"go/token"
+	"log"
+	"os"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"github.com/pfcm/osc/devicesim"
+)
+
+var (
+	inFlag      = flag.String("in", "", "`path` to the namespace description")
+	outFlag     = flag.String("out", "", "`path` to write the generated code to, or stdout if empty")
+	packageFlag = flag.String("package", "", "`name` of the generated package, defaults to one made from the description's name")
+)
+
+func main() {
+	flag.Parse()
+	if err := run(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run() error {
+	f, err := os.Open(*inFlag)
+	if err != nil {
+		return err
+	}
+	desc, err := devicesim.ParseDescription(f)
+	f.Close()
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", *inFlag, err)
+	}
+	pkg := *packageFlag
+	if pkg == "" {
+		pkg = packageName(desc.Name)
+	}
+	src, err := generate(desc, pkg, *inFlag)
+	if err != nil {
+		return err
+	}
+	if *outFlag == "" {
+		_, err := os.Stdout.Write(src)
+		return err
+	}
+	return os.WriteFile(*outFlag, src, 0o644)
+}
+
+// param is a parameter as seen by the template.
+type param struct {
+	Address string
+	// Name is the Go name for the parameter.
+	Name string
+	// Type is the Go type of its value, and Arg converts a value of
+	// that type called v to an osc.Argument.
+	Type, Arg string
+	// Tag is the OSC type tag, and OSCType the osc package's type for it.
+	Tag, OSCType string
+}
+
+// goTypes maps type tags to the Go type, the conversion to an osc.Argument and
+// the osc package's type.
+var goTypes = map[string][3]string{
+	"i": {"int32", "osc.AsInt32(v)", "Int32"},
+	"f": {"float32", "ptr(osc.Float32(v))", "Float32"},
+	"s": {"string", "osc.AsString(v)", "String"},
+}
+
+// generate returns the formatted source for a package with bindings for desc.
+func generate(desc devicesim.Description, pkg, source string) ([]byte, error) {
+	if !token.IsIdentifier(pkg) {
+		return nil, fmt.Errorf("expect a Go identifier for the package name, got: %q", pkg)
+	}
+	names := make(map[string]string)
+	var params []param
+	for _, p := range desc.Params {
+		name := goName(p.Address)
+		if prev, ok := names[name]; ok {
+			return nil, fmt.Errorf("addresses %q and %q would both be called %s", prev, p.Address, name)
+		}
+		names[name] = p.Address
+		t, ok := goTypes[p.Type]
+		if !ok {
+			return nil, fmt.Errorf("parameter %q: expect type i, f or s, got: %q", p.Address, p.Type)
+		}
+		params = append(params, param{p.Address, name, t[0], t[1], p.Type, t[2]})
+	}
+	var buf bytes.Buffer
+	err := tmpl.Execute(&buf, struct {
+		Package, Source, Device string
+		Params                  []param
+	}{pkg, source, desc.Name, params})
+	if err != nil {
+		return nil, err
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting generated code: %w", err)
+	}
+	return src, nil
+}
+
+// packageName turns a device name into a package name, by lower casing it and
+// dropping anything that can't be in an identifier, so "X32 Desk" becomes
+// x32desk.
+func packageName(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(unicode.ToLower(r))
+		}
+	}
+	pkg := b.String()
+	if pkg == "" || !unicode.IsLetter([]rune(pkg)[0]) || token.IsKeyword(pkg) {
+		pkg = "p" + pkg
+	}
+	return pkg
+}
+
+// goName turns an address into an exported Go identifier, by capitalising each
+// part and dropping anything that can't be in an identifier, so
+// "/ch/01/mix/fader" becomes Ch01MixFader.
+func goName(address string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range address {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	name := b.String()
+	if name == "" || !unicode.IsLetter([]rune(name)[0]) {
+		name = "P" + name
+	}
+	return name
+}
+
+// oneLine collapses whitespace in s, so it can't end a comment early.
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+var tmpl = template.Must(template.New("bindings").Funcs(template.FuncMap{"line": oneLine}).Parse(`// Code generated by oscgen from {{line .Source}}; DO NOT EDIT.
+
+// Package {{.Package}} has typed bindings for {{line .Device}}.
+package {{.Package}}
+
+import (
+{{- if .Params}}
+	"context"
+{{- end}}
+	"net"
+{{if .Params}}
+	"github.com/pfcm/osc"
+{{- end}}
+	"github.com/pfcm/osc/server"
+)
+
+// Client sends messages to {{line .Device}}.
+type Client struct {
+	Conn net.PacketConn
+	// Addr is the device's host:port.
+	Addr string
+}
+{{range .Params}}
+// Set{{.Name}} sets {{line .Address}}.
+func (c *Client) Set{{.Name}}(v {{.Type}}) error {
+	return osc.Send(c.Conn, c.Addr, {{printf "%q" .Address}}, {{.Arg}})
+}
+
+// Query{{.Name}} asks for the value of {{line .Address}}.
+func (c *Client) Query{{.Name}}() error {
+	return osc.Send(c.Conn, c.Addr, {{printf "%q" .Address}})
+}
+{{end}}
+// Handler is implemented by servers for {{line .Device}}. Each method is called
+// when its parameter is set.
+type Handler interface {
+{{- range .Params}}
+	// {{.Name}} handles {{line .Address}}.
+	{{.Name}}(ctx context.Context, v {{.Type}}) error
+{{- end}}
+}
+
+// Register registers h's methods with l, each on its address. Messages with
+// the wrong arguments are reported as errors rather than passed to h.
+func Register(l *server.Listener, h Handler) {
+{{- range .Params}}
+	l.Handle({{printf "%q" .Address}}, server.ContextHandlerFunc(func(ctx context.Context, msg *osc.Message) error {
+		if err := msg.CheckTypes({{printf "%q" .Tag}}); err != nil {
+			return err
+		}
+		return h.{{.Name}}(ctx, {{.Type}}(*msg.Arguments[0].(*osc.{{.OSCType}})))
+	}))
+{{- end}}
+}
+
+func ptr[T any](v T) *T { return &v }
+`))
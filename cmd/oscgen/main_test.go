@@ -0,0 +1,113 @@
+package main
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strings"
+	"testing"
+
+	"github.com/pfcm/osc/devicesim"
+)
+
+func TestGoName(t *testing.T) {
+	for _, c := range []struct {
+		address, want string
+	}{
+		{"/ch/01/mix/fader", "Ch01MixFader"},
+		{"/transport/play", "TransportPlay"},
+		{"/1/fader_2", "P1Fader2"},
+		{"/cue.go", "CueGo"},
+	} {
+		if got := goName(c.address); got != c.want {
+			t.Errorf("goName(%q) = %q, want: %q", c.address, got, c.want)
+		}
+	}
+}
+
+func TestPackageName(t *testing.T) {
+	for _, c := range []struct {
+		name, want string
+	}{
+		{"desk", "desk"},
+		{"X32 Desk", "x32desk"},
+		{"1-desk", "p1desk"},
+		{"go", "pgo"},
+		{"", "p"},
+	} {
+		if got := packageName(c.name); got != c.want {
+			t.Errorf("packageName(%q) = %q, want: %q", c.name, got, c.want)
+		}
+	}
+}
+
+// typeCheck parses and type-checks generated source, returning the file.
+func typeCheck(t *testing.T, src []byte) *ast.File {
+	t.Helper()
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "gen.go", src, parser.ParseComments)
+	if err != nil {
+		t.Fatalf("generated code doesn't parse: %v\n%s", err, src)
+	}
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	if _, err := conf.Check(f.Name.Name, fset, []*ast.File{f}, nil); err != nil {
+		t.Fatalf("generated code doesn't compile: %v\n%s", err, src)
+	}
+	return f
+}
+
+func TestGenerate(t *testing.T) {
+	desc := devicesim.Description{
+		Name: "desk",
+		Params: []devicesim.Param{
+			{Address: "/ch/01/mix/fader", Type: "f"},
+			{Address: "/ch/01/mix/on", Type: "i"},
+			{Address: "/ch/01/config/name", Type: "s"},
+		},
+	}
+	src, err := generate(desc, "desk", "desk.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f := typeCheck(t, src); f.Name.Name != "desk" {
+		t.Errorf("generated package %s, want: desk", f.Name.Name)
+	}
+	for _, want := range []string{
+		"package desk",
+		"func (c *Client) SetCh01MixFader(v float32) error",
+		"func (c *Client) QueryCh01ConfigName() error",
+		"Ch01MixOn(ctx context.Context, v int32) error",
+	} {
+		if !strings.Contains(string(src), want) {
+			t.Errorf("generated code missing %q:\n%s", want, src)
+		}
+	}
+
+	// Names from the description can't break out of comments.
+	desc.Name = "X32\n}\nfunc init() { panic(1) }\n//"
+	src, err = generate(desc, packageName(desc.Name), "desk.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	typeCheck(t, src)
+	if strings.Contains(string(src), "\nfunc init()") {
+		t.Errorf("description name escaped a comment:\n%s", src)
+	}
+	if _, err := generate(desc, "not a package", "desk.json"); err == nil {
+		t.Error("generate() with an invalid package name = nil error, want error")
+	}
+
+	// A device with no parameters still compiles.
+	src, err = generate(devicesim.Description{Name: "empty"}, "empty", "empty.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	typeCheck(t, src)
+
+	desc.Params = append(desc.Params, devicesim.Param{Address: "/ch/01/mix.fader", Type: "f"})
+	if _, err := generate(desc, "desk", "desk.json"); err == nil {
+		t.Error("generate() with clashing names = nil error, want error")
+	}
+}
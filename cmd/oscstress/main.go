@@ -0,0 +1,150 @@
+// oscstress floods an address with OSC packets at a target rate, to load-test
+// receivers. If no address is given it starts its own receiver on loopback, and
+// reports how many of the valid messages were dropped.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"math/rand"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/pfcm/osc"
+	"github.com/pfcm/osc/server"
+)
+
+var (
+	sendAddrFlag = flag.String("send_addr", "", "`host:port` to send to, if empty a receiver is started on loopback and drops are reported")
+	rateFlag     = flag.Int("rate", 10000, "target `packets per second`")
+	durationFlag = flag.Duration("duration", 10*time.Second, "how long to send for")
+	invalidFlag  = flag.Float64("invalid", 0, "`fraction` of packets to make invalid")
+	patternFlag  = flag.String("pattern", "/stress", "`address pattern` to send messages to")
+	argsFlag     = flag.Int("args", 4, "`number` of arguments in each message")
+	workersFlag  = flag.Int("workers", 4, "`number` of workers for the loopback receiver")
+)
+
+func main() {
+	flag.Parse()
+	ctx := context.Background()
+	if err := run(ctx); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run(ctx context.Context) error {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var (
+		received atomic.Int64
+		addr     net.Addr
+	)
+	if *sendAddrFlag == "" {
+		rconn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		if err != nil {
+			return err
+		}
+		defer rconn.Close()
+		l := server.NewListener(rconn, *workersFlag)
+		l.Handle(*patternFlag, server.HandlerFunc(func(*osc.Message) error {
+			received.Add(1)
+			return nil
+		}))
+		rctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		go l.Serve(rctx)
+		addr = rconn.LocalAddr()
+	} else {
+		addr, err = net.ResolveUDPAddr("udp", *sendAddrFlag)
+		if err != nil {
+			return err
+		}
+	}
+	log.Printf("Sending %d packets/s to %v for %v", *rateFlag, addr, *durationFlag)
+
+	valid, invalid, elapsed, err := flood(conn, addr)
+	if err != nil {
+		return err
+	}
+	sent := valid + invalid
+	log.Printf("Sent %d packets (%d invalid) in %v: %.0f packets/s",
+		sent, invalid, elapsed, float64(sent)/elapsed.Seconds())
+
+	if *sendAddrFlag == "" {
+		// Give the receiver a moment to catch up.
+		time.Sleep(500 * time.Millisecond)
+		got := received.Load()
+		log.Printf("Received %d of %d valid messages, dropped %d (%.2f%%)",
+			got, valid, valid-got, 100*float64(valid-got)/float64(max(valid, 1)))
+	}
+	return nil
+}
+
+// flood sends packets at the target rate until the duration is up.
+func flood(conn net.PacketConn, addr net.Addr) (valid, invalid int64, elapsed time.Duration, err error) {
+	interval := time.Second / time.Duration(max(*rateFlag, 1))
+	start := time.Now()
+	buf := make([]byte, 0, 1024)
+	for i := time.Duration(0); ; i++ {
+		now := time.Since(start)
+		if now >= *durationFlag {
+			return valid, invalid, now, nil
+		}
+		if due := i * interval; due > now {
+			time.Sleep(due - now)
+		}
+		buf = message().Append(buf[:0])
+		if rand.Float64() < *invalidFlag {
+			buf = corrupt(buf)
+			invalid++
+		} else {
+			valid++
+		}
+		if _, err := conn.WriteTo(buf, addr); err != nil {
+			return valid, invalid, time.Since(start), err
+		}
+	}
+}
+
+func message() *osc.Message {
+	args := make([]osc.Argument, *argsFlag)
+	for i := range args {
+		switch rand.Intn(3) {
+		case 0:
+			args[i] = osc.AsInt32(rand.Int31())
+		case 1:
+			f := osc.Float32(rand.Float32())
+			args[i] = &f
+		case 2:
+			args[i] = osc.AsString("stress")
+		}
+	}
+	return &osc.Message{Pattern: *patternFlag, Arguments: args}
+}
+
+// corrupt breaks an encoded message in one of a few ways.
+func corrupt(b []byte) []byte {
+	switch rand.Intn(3) {
+	case 0:
+		// Truncated, by at least 4 bytes so it's not just padding
+		// missing from the end.
+		return b[:rand.Intn(len(b)-3)]
+	case 1:
+		// Garbage.
+		rand.Read(b)
+		return b
+	default:
+		// Missing type tag, which starts at the first 4 byte boundary
+		// after the address.
+		i := len(*patternFlag) + 1
+		i += (4 - i%4) % 4
+		b[i] = 'x'
+		return b
+	}
+}
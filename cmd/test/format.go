@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/pfcm/osc"
+	"github.com/pfcm/osc/server"
+)
+
+// printer writes a received message to the output in some format.
+type printer func(meta server.Meta, msg *osc.Message) error
+
+// newPrinter returns the printer for format, which may be empty to print
+// nothing:
+//   - hex: the encoded message as a line of hex, which can be replayed.
+//   - text: the message as a line in the format of osc.ParseText.
+//   - csv: received time, sender, address and type tag, then a column per
+//     argument in the text format.
+func newPrinter(format string, w io.Writer) (printer, error) {
+	switch format {
+	case "":
+		return func(server.Meta, *osc.Message) error { return nil }, nil
+	case "hex":
+		return func(_ server.Meta, msg *osc.Message) error {
+			_, err := fmt.Fprintln(w, hex.EncodeToString(msg.Append(nil)))
+			return err
+		}, nil
+	case "text":
+		return func(_ server.Meta, msg *osc.Message) error {
+			line, err := osc.FormatText(msg)
+			if err != nil {
+				return err
+			}
+			_, err = fmt.Fprintln(w, line)
+			return err
+		}, nil
+	case "csv":
+		cw := csv.NewWriter(w)
+		return func(meta server.Meta, msg *osc.Message) error {
+			var from string
+			if meta.Addr != nil {
+				from = meta.Addr.String()
+			}
+			record := []string{meta.Received.Format(time.RFC3339Nano), from, msg.Pattern, msg.TypeTag()}
+			for i, a := range msg.Arguments {
+				f, err := osc.FormatTextArgument(a)
+				if err != nil {
+					return fmt.Errorf("argument %d: %w", i, err)
+				}
+				record = append(record, f)
+			}
+			cw.Write(record)
+			cw.Flush()
+			return cw.Error()
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown format %q, want one of \"hex\", \"text\" or \"csv\"", format)
+}
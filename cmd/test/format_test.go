@@ -0,0 +1,43 @@
+package main
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/pfcm/osc"
+	"github.com/pfcm/osc/server"
+)
+
+func TestPrinter(t *testing.T) {
+	meta := server.Meta{
+		Addr:     &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 9000},
+		Received: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+	}
+	f := osc.Float32(0.5)
+	msg := &osc.Message{Pattern: "/fader", Arguments: []osc.Argument{&f, osc.AsString("a, b")}}
+	for _, c := range []struct {
+		format, want string
+	}{
+		{"", ""},
+		{"hex", "2f666164657200002c6673003f000000612c206200000000\n"},
+		{"text", "/fader 0.5 \"a, b\"\n"},
+		{"csv", "2024-05-01T12:00:00Z,127.0.0.1:9000,/fader,fs,0.5,\"\"\"a, b\"\"\"\n"},
+	} {
+		var buf bytes.Buffer
+		p, err := newPrinter(c.format, &buf)
+		if err != nil {
+			t.Fatalf("newPrinter(%q): %v", c.format, err)
+		}
+		if err := p(meta, msg); err != nil {
+			t.Errorf("%q printer: %v", c.format, err)
+		}
+		if got := buf.String(); got != c.want {
+			t.Errorf("%q printer wrote %q, want: %q", c.format, got, c.want)
+		}
+	}
+	if _, err := newPrinter("json", nil); err == nil {
+		t.Error(`newPrinter("json") = nil error, want error`)
+	}
+}
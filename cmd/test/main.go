@@ -3,18 +3,23 @@ package main
 import (
 	"context"
 	"flag"
+	"fmt"
 	"log"
 	"net"
+	"os"
 
 	"github.com/pfcm/osc"
 	"github.com/pfcm/osc/server"
 )
 
 var (
-	modeFlag       = flag.String("mode", "", "`mode` in which to run, must be one of \"send\" or \"receive\"")
+	modeFlag       = flag.String("mode", "", "`mode` in which to run, must be one of \"send\", \"receive\" or \"forward\"")
 	listenAddrFlag = flag.String("listen_addr", "127.0.0.1:0", "`host:port`: the address to listen on.")
 	sendAddrFlag   = flag.String("send_addr", "", "`host:port`: the address to send to.")
 	patternFlag    = flag.String("pattern", "/test", "`address pattern` to to send a message to, in send mode")
+	rulesFlag      = flag.String("rules", "", "`path` to a file of rules to transform messages with, in forward mode")
+	dumpFlag       = flag.Bool("dump", false, "print an annotated hexdump of each message, in receive mode")
+	formatFlag     = flag.String("format", "", "`format` to print each message to stdout in, in receive mode: \"hex\", \"text\" or \"csv\"")
 )
 
 func main() {
@@ -30,6 +35,10 @@ func main() {
 		if err := receive(ctx); err != nil {
 			log.Fatal(err)
 		}
+	case "forward":
+		if err := forward(ctx); err != nil {
+			log.Fatal(err)
+		}
 	default:
 		log.Fatalf("unknown mode %q", *modeFlag)
 	}
@@ -57,6 +66,10 @@ func send(ctx context.Context) error {
 }
 
 func receive(ctx context.Context) error {
+	out, err := newPrinter(*formatFlag, os.Stdout)
+	if err != nil {
+		return err
+	}
 	conn, err := net.ListenPacket("udp", *listenAddrFlag)
 	if err != nil {
 		return err
@@ -70,10 +83,52 @@ func receive(ctx context.Context) error {
 		"/test/b",
 		"/test/c",
 	} {
-		l.Handle(p, server.HandlerFunc(func(msg *osc.Message) error {
+		l.Handle(p, server.ContextHandlerFunc(func(ctx context.Context, msg *osc.Message) error {
 			log.Printf("%s: recv: %v", p, msg)
-			return nil
+			if *dumpFlag {
+				fmt.Print(msg.AnnotatedDump())
+			}
+			meta, _ := server.MetaFromContext(ctx)
+			return out(meta, msg)
 		}))
 	}
 	return l.Serve(ctx)
 }
+
+// forward receives messages and sends them on to send_addr, transformed by the
+// rules in the rules file.
+func forward(ctx context.Context) error {
+	var rules []rule
+	if *rulesFlag != "" {
+		f, err := os.Open(*rulesFlag)
+		if err != nil {
+			return err
+		}
+		rules, err = parseRules(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("reading %s: %w", *rulesFlag, err)
+		}
+	}
+	conn, err := net.ListenPacket("udp", *listenAddrFlag)
+	if err != nil {
+		return err
+	}
+	log.Printf("Forwarding from %v to %v with %d rules", conn.LocalAddr(), *sendAddrFlag, len(rules))
+
+	// Every message should match, so treat the handler's address as the
+	// pattern.
+	l := server.NewListener(conn, 1, server.WithMatchDirection(server.HandlerPattern))
+	l.Handle("*", server.HandlerFunc(func(msg *osc.Message) error {
+		out := apply(rules, msg)
+		switch {
+		case out == nil:
+			log.Printf("- %v", msg)
+			return nil
+		case out != msg:
+			log.Printf("- %v\n+ %v", msg, out)
+		}
+		return osc.Send(conn, *sendAddrFlag, out.Pattern, out.Arguments...)
+	}))
+	return l.Serve(ctx)
+}
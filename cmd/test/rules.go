@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/pfcm/osc"
+	"github.com/pfcm/osc/server"
+)
+
+// rule is a transformation applied to forwarded messages. Rules files have one
+// rule per line, blank lines and lines starting with # are ignored:
+//
+//	drop <pattern>              don't forward matching messages
+//	rewrite <pattern> <address> forward matching messages to a new address
+//
+// The first rule with a pattern matching a message's address applies, and
+// messages matching no rules are forwarded unchanged.
+type rule struct {
+	pattern string
+	drop    bool
+	address string
+}
+
+func parseRules(r io.Reader) ([]rule, error) {
+	var rules []rule
+	s := bufio.NewScanner(r)
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		var ru rule
+		switch {
+		case fields[0] == "drop" && len(fields) == 2:
+			ru = rule{pattern: fields[1], drop: true}
+		case fields[0] == "rewrite" && len(fields) == 3:
+			ru = rule{pattern: fields[1], address: fields[2]}
+		default:
+			return nil, fmt.Errorf("line %d: invalid rule %q", n, line)
+		}
+		if _, err := server.Match(ru.pattern, ""); err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		if !ru.drop {
+			if err := (osc.Message{Pattern: ru.address}).Validate(); err != nil {
+				return nil, fmt.Errorf("line %d: rewriting to %q: %w", n, ru.address, err)
+			}
+		}
+		rules = append(rules, ru)
+	}
+	return rules, s.Err()
+}
+
+// apply returns the message to forward, or nil if it should be dropped.
+func apply(rules []rule, msg *osc.Message) *osc.Message {
+	for _, r := range rules {
+		if ok, _ := server.Match(r.pattern, msg.Pattern); !ok {
+			continue
+		}
+		if r.drop {
+			return nil
+		}
+		return &osc.Message{Pattern: r.address, Arguments: msg.Arguments}
+	}
+	return msg
+}
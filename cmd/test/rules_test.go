@@ -0,0 +1,82 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pfcm/osc"
+)
+
+func TestParseRules(t *testing.T) {
+	rules, err := parseRules(strings.NewReader(`
+# Comments and blank lines are ignored.
+
+drop /meter/*
+  rewrite /ch/*/fader   /mix/fader
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []rule{
+		{pattern: "/meter/*", drop: true},
+		{pattern: "/ch/*/fader", address: "/mix/fader"},
+	}
+	if !reflect.DeepEqual(rules, want) {
+		t.Errorf("parseRules() = %+v, want: %+v", rules, want)
+	}
+
+	for _, c := range []struct {
+		rules, err string
+	}{
+		{"drop", "line 1: invalid rule"},
+		{"drop /a /b", "line 1: invalid rule"},
+		{"# ok\nrewrite /a", "line 2: invalid rule"},
+		{"\nkeep /a", "line 2: invalid rule"},
+		{"drop /a\ndrop /mixer/[1", "line 2:"},
+		{"rewrite /a b", "line 1: rewriting to \"b\""},
+	} {
+		_, err := parseRules(strings.NewReader(c.rules))
+		if err == nil || !strings.HasPrefix(err.Error(), c.err) {
+			t.Errorf("parseRules(%q) = %v, want error starting: %q", c.rules, err, c.err)
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	rules, err := parseRules(strings.NewReader(`
+rewrite /ch/1/fader /kick
+drop /ch/*/fader
+rewrite /ch/*/fader /never
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	args := []osc.Argument{osc.AsInt32(1)}
+	for _, c := range []struct {
+		pattern string
+		// want is the forwarded address, or empty if dropped.
+		want string
+	}{
+		// The first matching rule wins.
+		{"/ch/1/fader", "/kick"},
+		{"/ch/2/fader", ""},
+		// Nothing matches, so forwarded unchanged.
+		{"/ch/2/mute", "/ch/2/mute"},
+	} {
+		msg := &osc.Message{Pattern: c.pattern, Arguments: args}
+		out := apply(rules, msg)
+		switch {
+		case c.want == "" && out != nil:
+			t.Errorf("apply(%s) = %v, want dropped", c.pattern, out)
+		case c.want == "":
+		case out == nil:
+			t.Errorf("apply(%s) dropped, want: %s", c.pattern, c.want)
+		case out.Pattern != c.want || !reflect.DeepEqual(out.Arguments, args):
+			t.Errorf("apply(%s) = %v, want: %s with the same arguments", c.pattern, out, c.want)
+		}
+	}
+	if msg := (&osc.Message{Pattern: "/x"}); apply(rules, msg) != msg {
+		t.Error("apply() of an unmatched message returned a copy, want it unchanged")
+	}
+}
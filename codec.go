@@ -0,0 +1,157 @@
+package osc
+
+import (
+	"fmt"
+	"maps"
+	"sync"
+	"sync/atomic"
+)
+
+// Codec parses messages, using its own set of argument types. Different parts
+// of a program can use different Codecs to speak different dialects of OSC
+// without interfering with each other. Encoding needs no Codec, as each
+// argument encodes itself. The zero Codec parses like ParseMessage.
+type Codec struct {
+	// types, if nil, are the registered types.
+	types map[rune]func() Argument
+	// interner, if set, is used for the pattern and String arguments.
+	interner *Interner
+}
+
+// defaultCodec is used by ParseMessage.
+var defaultCodec = &Codec{}
+
+// registry holds the types added with RegisterType as well as the standard
+// ones, or is nil if there have been none. It's replaced, never modified in
+// place, so parsing can read it while registryMu is held to change it.
+var (
+	registryMu sync.Mutex
+	registry   atomic.Pointer[map[rune]func() Argument]
+)
+
+// RegisterType adds an argument type understood by ParseMessage, Signatures
+// and Codecs created afterwards by NewCodec, replacing any existing type with
+// the same tag. mk must return a new Argument with the given type tag, ready
+// to Consume. It's safe to call at any time, but is usually called from an
+// init function so every message is parsed the same way.
+func RegisterType(tag rune, mk func() Argument) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	types := maps.Clone(registeredTypes())
+	types[tag] = mk
+	registry.Store(&types)
+}
+
+// registeredTypes returns the types understood by ParseMessage, which must not
+// be modified.
+func registeredTypes() map[rune]func() Argument {
+	if types := registry.Load(); types != nil {
+		return *types
+	}
+	return newByTypeTag
+}
+
+// NewCodec returns a Codec that understands the standard argument types, and
+// any added so far with RegisterType.
+func NewCodec() *Codec {
+	return &Codec{types: maps.Clone(registeredTypes())}
+}
+
+// Register adds an argument type to the Codec, replacing any existing type
+// with the same tag. mk must return a new Argument with the given type tag,
+// ready to Consume. Register must not be called while the Codec is in use.
+func (c *Codec) Register(tag rune, mk func() Argument) {
+	if c.types == nil {
+		c.types = maps.Clone(registeredTypes())
+	}
+	c.types[tag] = mk
+}
+
+// WithInterner returns a copy of the Codec that takes the pattern and String
+// arguments of parsed messages from in, so repeated values share memory
+// rather than each being allocated.
+func (c *Codec) WithInterner(in *Interner) *Codec {
+	return &Codec{types: maps.Clone(c.types), interner: in}
+}
+
+// ParseMessage parses a message.
+func (c *Codec) ParseMessage(buf []byte) (*Message, error) {
+	m := new(Message)
+	if err := c.ParseMessageInto(m, buf); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// ParseMessageInto parses a message into m, reusing its memory where
+// possible: the pattern is kept if it's unchanged and arguments are reused if
+// they have the right type, including those beyond the length of m.Arguments
+// left by Message.Reset. There's no need to Reset m first, everything is
+// overwritten. If parsing fails m is left in an undefined state.
+func (c *Codec) ParseMessageInto(m *Message, buf []byte) error {
+	if len(buf) == 0 {
+		return ErrEmptyPacket
+	}
+	// A message begins with the address, which is a string.
+	addr, buf, err := consumeString(buf)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrBadAddress, err)
+	}
+	// This comparison doesn't allocate.
+	if m.Pattern != string(addr) {
+		m.Pattern = c.string(addr)
+	}
+	// Next is the type tag string.
+	tt, buf, err := consumeString(buf)
+	if err != nil {
+		return fmt.Errorf("reading type tag: %w", err)
+	}
+	if len(tt) == 0 || tt[0] != ',' {
+		// TODO: the spec talks about handling this case, but it is
+		// unclear how.
+		return fmt.Errorf("invalid type tag string %q: %w", tt, ErrBadTypeTag{})
+	}
+	types := c.types
+	if types == nil {
+		types = registeredTypes()
+	}
+	args := m.Arguments[:0]
+	if args == nil {
+		args = make([]Argument, 0, len(tt)-1)
+	}
+	for i, t := range string(tt[1:]) {
+		var a Argument
+		if len(args) < cap(args) {
+			a = args[:len(args)+1][len(args)]
+		}
+		if a == nil || a.TypeTag() != t {
+			mk, ok := types[t]
+			if !ok {
+				return ErrBadTypeTag{Tag: t}
+			}
+			a = mk()
+		}
+		if s, ok := a.(*String); ok && c.interner != nil {
+			var str []byte
+			if str, buf, err = consumeString(buf); err == nil {
+				*s = String(c.interner.Intern(str))
+			}
+		} else {
+			buf, err = a.Consume(buf)
+		}
+		if err != nil {
+			return fmt.Errorf("reading argument %d (%c): %w", i, t, err)
+		}
+		args = append(args, a)
+	}
+	m.Arguments = args
+	return nil
+}
+
+// string converts b to a string, using the interner if there is one.
+func (c *Codec) string(b []byte) string {
+	if c.interner != nil {
+		return c.interner.Intern(b)
+	}
+	return string(b)
+}
@@ -0,0 +1,106 @@
+package osc
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// vendorInt is a made up argument type.
+type vendorInt struct{ Int32 }
+
+func (vendorInt) TypeTag() rune { return 'v' }
+
+func (v vendorInt) String() string {
+	return fmt.Sprintf("vendorInt(%d)", v.Int32)
+}
+
+func TestCodec(t *testing.T) {
+	msg := &Message{
+		Pattern:   "/vendor",
+		Arguments: []Argument{&vendorInt{7}, AsString("standard")},
+	}
+	enc := msg.Append(nil)
+
+	if _, err := ParseMessage(enc); err == nil {
+		t.Error("ParseMessage understood vendor type without registration")
+	}
+	c := NewCodec()
+	c.Register('v', func() Argument { return new(vendorInt) })
+	got, err := c.ParseMessage(enc)
+	if err != nil {
+		t.Fatalf("Codec.ParseMessage: %v", err)
+	}
+	if !reflect.DeepEqual(got, msg) {
+		t.Errorf("Codec.ParseMessage = %v, want: %v", got, msg)
+	}
+	// Other codecs should be unaffected.
+	if _, err := NewCodec().ParseMessage(enc); err == nil {
+		t.Error("registration leaked into another Codec")
+	}
+	if _, err := ParseMessage(enc); err == nil {
+		t.Error("registration leaked into ParseMessage")
+	}
+}
+
+// registeredInt is a made up argument type for RegisterType, distinct from
+// vendorInt so the tests can't interfere.
+type registeredInt struct{ Int32 }
+
+func (registeredInt) TypeTag() rune { return 'w' }
+
+// restoreRegistry puts back the registered types as they are now once the test
+// finishes, as RegisterType can't otherwise be undone.
+func restoreRegistry(t *testing.T) {
+	registryMu.Lock()
+	saved := registry.Load()
+	registryMu.Unlock()
+	t.Cleanup(func() {
+		registryMu.Lock()
+		defer registryMu.Unlock()
+		registry.Store(saved)
+	})
+}
+
+func TestRegisterType(t *testing.T) {
+	restoreRegistry(t)
+	msg := &Message{
+		Pattern:   "/registered",
+		Arguments: []Argument{&registeredInt{7}},
+	}
+	enc := msg.Append(nil)
+	if _, err := ParseMessage(enc); err == nil {
+		t.Fatal("ParseMessage understood type before registration")
+	}
+	before := NewCodec()
+
+	// Registering while parsing is safe.
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for range 100 {
+			ParseMessage(enc)
+		}
+	}()
+	RegisterType('w', func() Argument { return new(registeredInt) })
+	wg.Wait()
+
+	got, err := ParseMessage(enc)
+	if err != nil {
+		t.Fatalf("ParseMessage after RegisterType: %v", err)
+	}
+	if !reflect.DeepEqual(got, msg) {
+		t.Errorf("ParseMessage = %v, want: %v", got, msg)
+	}
+	if _, err := NewCodec().ParseMessage(enc); err != nil {
+		t.Errorf("NewCodec after RegisterType: %v", err)
+	}
+	if _, err := before.ParseMessage(enc); err == nil {
+		t.Error("RegisterType changed a Codec created before it")
+	}
+	if _, err := ParseSignature("iw"); err != nil {
+		t.Errorf(`ParseSignature("iw") = %v, want: nil`, err)
+	}
+}
@@ -0,0 +1,180 @@
+package osc
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"slices"
+)
+
+// Compression identifies how the data in a Compressed argument is encoded.
+type Compression byte
+
+const (
+	// NoCompression stores the data as is.
+	NoCompression Compression = iota
+	// Gzip compresses the data with gzip.
+	Gzip
+)
+
+func (c Compression) String() string {
+	switch c {
+	case NoCompression:
+		return "none"
+	case Gzip:
+		return "gzip"
+	}
+	return fmt.Sprintf("Compression(%d)", byte(c))
+}
+
+// maxDecompressed limits how large a Compressed argument may expand, so a
+// small packet can't exhaust our memory.
+const maxDecompressed = 1 << 24
+
+// Compressed is a non-standard argument carrying a blob, compressed on the wire.
+// It's encoded as a blob whose first byte is the Compression method and the
+// remainder is the compressed data. Peers should only be sent Compressed
+// arguments if they've advertised support, see Capabilities.
+type Compressed struct {
+	Method Compression
+	Data   Blob
+}
+
+// CompressBlob returns b as a Compressed argument using gzip if it's at least
+// threshold bytes long, otherwise it returns b unchanged.
+func CompressBlob(b Blob, threshold int) Argument {
+	if len(b) < threshold {
+		return &b
+	}
+	return &Compressed{Method: Gzip, Data: b}
+}
+
+func (Compressed) TypeTag() rune { return 'z' }
+
+func (c Compressed) Append(b []byte) []byte {
+	buf := bytes.NewBuffer([]byte{byte(c.Method)})
+	switch c.Method {
+	case Gzip:
+		// Writes to a bytes.Buffer can't fail.
+		w := gzip.NewWriter(buf)
+		w.Write(c.Data)
+		w.Close()
+	default:
+		buf.Write(c.Data)
+	}
+	return Blob(buf.Bytes()).Append(b)
+}
+
+func (c *Compressed) Consume(b []byte) ([]byte, error) {
+	var raw Blob
+	b, err := raw.Consume(b)
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) == 0 {
+		return nil, fmt.Errorf("compressed blob has no method: %w", ErrShortBuffer)
+	}
+	c.Method, raw = Compression(raw[0]), raw[1:]
+	switch c.Method {
+	case NoCompression:
+		c.Data = raw
+	case Gzip:
+		r, err := gzip.NewReader(bytes.NewReader(raw))
+		if err != nil {
+			return nil, fmt.Errorf("decompressing blob: %w", err)
+		}
+		data, err := io.ReadAll(io.LimitReader(r, maxDecompressed+1))
+		if err != nil {
+			return nil, fmt.Errorf("decompressing blob: %w", err)
+		}
+		if len(data) > maxDecompressed {
+			return nil, fmt.Errorf("decompressed blob larger than %d bytes", maxDecompressed)
+		}
+		c.Data = data
+	default:
+		return nil, fmt.Errorf("unknown compression method %v", c.Method)
+	}
+	return b, nil
+}
+
+func (c Compressed) String() string {
+	return fmt.Sprintf("Compressed(%v, %x)", c.Method, []byte(c.Data))
+}
+
+// CapabilitiesAddress is the address of messages advertising a peer's optional
+// features.
+const CapabilitiesAddress = "/capabilities"
+
+// Capabilities that may be advertised.
+const (
+	// CapGzip means a peer can decode Compressed arguments using Gzip.
+	CapGzip = "compress/gzip"
+	// CapSLIP means a peer accepts packets over streams framed with
+	// double-ended SLIP, as in OSC 1.1.
+	CapSLIP = "transport/slip"
+	// CapTCP means a peer accepts connections over TCP.
+	CapTCP = "transport/tcp"
+)
+
+// MandatoryTypeTags are the argument types every OSC 1.1 implementation must
+// support. This package supports all of them.
+const MandatoryTypeTags = "ifsbtTFNI"
+
+// Capabilities is a set of optional features supported by a peer. They are
+// exchanged as a message to CapabilitiesAddress with a String argument for
+// each feature.
+type Capabilities map[string]bool
+
+// Message returns the message advertising the capabilities, sorted by name so
+// the same set always encodes the same way.
+func (c Capabilities) Message() *Message {
+	return c.message(CapabilitiesAddress)
+}
+
+func (c Capabilities) message(pattern string) *Message {
+	var names []string
+	for cap, ok := range c {
+		if ok {
+			names = append(names, cap)
+		}
+	}
+	slices.Sort(names)
+	msg := &Message{Pattern: pattern}
+	for _, cap := range names {
+		msg.Arguments = append(msg.Arguments, AsString(cap))
+	}
+	return msg
+}
+
+// Intersect returns the capabilities in both c and o, the features two peers
+// can use with each other.
+func (c Capabilities) Intersect(o Capabilities) Capabilities {
+	both := make(Capabilities)
+	for cap, ok := range c {
+		if ok && o[cap] {
+			both[cap] = true
+		}
+	}
+	return both
+}
+
+// ParseCapabilities reads the capabilities advertised in a message.
+func ParseCapabilities(msg *Message) (Capabilities, error) {
+	return parseCapabilities(msg, CapabilitiesAddress)
+}
+
+func parseCapabilities(msg *Message, pattern string) (Capabilities, error) {
+	if msg.Pattern != pattern {
+		return nil, fmt.Errorf("capabilities must be sent to %q, not %q", pattern, msg.Pattern)
+	}
+	c := make(Capabilities, len(msg.Arguments))
+	for i, a := range msg.Arguments {
+		s, ok := a.(*String)
+		if !ok {
+			return nil, fmt.Errorf("capability %d: expect string, got %v", i, a)
+		}
+		c[string(*s)] = true
+	}
+	return c, nil
+}
@@ -0,0 +1,248 @@
+// Package devicesim emulates OSC devices from a declarative description, so
+// programs can be developed against a stand-in for a mixing desk or show
+// controller without the hardware.
+//
+// A simulated device holds a value for each of its parameters. A message with
+// an argument sets the parameter, clamped to its range, and a message with no
+// arguments is a query, answered with the current value. Replies are sent
+// back to the sender after the device's latency.
+package devicesim
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pfcm/osc"
+	"github.com/pfcm/osc/server"
+)
+
+// Description describes a device. It's usually loaded from JSON with
+// ParseDescription, for example:
+//
+//	{
+//	  "name": "desk",
+//	  "latency": "5ms",
+//	  "params": [
+//	    {"address": "/ch/01/mix/fader", "type": "f", "max": 1, "echo": true},
+//	    {"address": "/ch/01/mix/on", "type": "i", "max": 1, "default": 1},
+//	    {"address": "/ch/01/config/name", "type": "s", "default": "Kick"}
+//	  ]
+//	}
+type Description struct {
+	Name string `json:"name"`
+	// Latency delays every reply from the device.
+	Latency Duration `json:"latency"`
+	Params  []Param  `json:"params"`
+}
+
+// Param is a single value on a device.
+type Param struct {
+	Address string `json:"address"`
+	// Type is the OSC type tag of the value, one of "i", "f" or "s".
+	Type string `json:"type"`
+	// Min and Max are the range of numeric values, which are clamped to
+	// it. If they're both zero the value isn't limited.
+	Min float64 `json:"min"`
+	Max float64 `json:"max"`
+	// Default is the initial value, a number or a string depending on
+	// Type.
+	Default any `json:"default"`
+	// Echo sends the new value back to the sender whenever it's set, as
+	// many devices do to confirm changes.
+	Echo bool `json:"echo"`
+}
+
+// Duration is a time.Duration written in JSON as a string, such as "10ms".
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("expect duration string, got: %s", b)
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// ParseDescription reads a JSON device description.
+func ParseDescription(r io.Reader) (Description, error) {
+	var d Description
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&d); err != nil {
+		return Description{}, err
+	}
+	return d, nil
+}
+
+// Device is a simulated device.
+type Device struct {
+	desc   Description
+	params map[string]Param
+
+	mu     sync.Mutex
+	values map[string]osc.Argument
+}
+
+// New returns a Device behaving as described, with every parameter set to its
+// default.
+func New(desc Description) (*Device, error) {
+	d := &Device{
+		desc:   desc,
+		params: make(map[string]Param, len(desc.Params)),
+		values: make(map[string]osc.Argument, len(desc.Params)),
+	}
+	for _, p := range desc.Params {
+		if _, ok := d.params[p.Address]; ok {
+			return nil, fmt.Errorf("duplicate parameter %q", p.Address)
+		}
+		if !strings.HasPrefix(p.Address, "/") {
+			return nil, fmt.Errorf("expect address starting with /, got: %q", p.Address)
+		}
+		v, err := p.initial()
+		if err != nil {
+			return nil, fmt.Errorf("parameter %q: %w", p.Address, err)
+		}
+		d.params[p.Address] = p
+		d.values[p.Address] = v
+	}
+	return d, nil
+}
+
+// initial returns the default value of a parameter.
+func (p Param) initial() (osc.Argument, error) {
+	switch v := p.Default.(type) {
+	case nil:
+		if p.Type == "s" {
+			return osc.AsString(""), nil
+		}
+		return p.number(0)
+	case float64:
+		return p.number(v)
+	case string:
+		return p.string(v)
+	}
+	return nil, fmt.Errorf("expect number or string default, got: %v", p.Default)
+}
+
+// number returns v as the parameter's type, clamped to its range.
+func (p Param) number(v float64) (osc.Argument, error) {
+	if p.Min != 0 || p.Max != 0 {
+		v = min(max(v, p.Min), p.Max)
+	}
+	switch p.Type {
+	case "i":
+		return osc.AsInt32(int32(v)), nil
+	case "f":
+		f := osc.Float32(v)
+		return &f, nil
+	}
+	return nil, fmt.Errorf("expect %s value, got number %v", p.typeName(), v)
+}
+
+// string returns v as a String, if that's the parameter's type.
+func (p Param) string(v string) (osc.Argument, error) {
+	if p.Type != "s" {
+		return nil, fmt.Errorf("expect %s value, got string %q", p.typeName(), v)
+	}
+	return osc.AsString(v), nil
+}
+
+func (p Param) typeName() string {
+	switch p.Type {
+	case "i":
+		return "int32"
+	case "f":
+		return "float32"
+	case "s":
+		return "string"
+	}
+	return fmt.Sprintf("unknown type %q", p.Type)
+}
+
+// Value returns the current value of the parameter at address, or nil if the
+// device has no such parameter.
+func (d *Device) Value(address string) osc.Argument {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.values[address]
+}
+
+// Serve runs the device on conn until the context is cancelled, or there's an
+// error from the connection.
+func (d *Device) Serve(ctx context.Context, conn net.PacketConn) error {
+	l := server.NewListener(conn, 1)
+	for _, p := range d.desc.Params {
+		l.Handle(p.Address, server.ContextHandlerFunc(func(ctx context.Context, msg *osc.Message) error {
+			v, reply, err := d.handle(p, msg)
+			if err != nil || !reply {
+				return err
+			}
+			meta, _ := server.MetaFromContext(ctx)
+			d.reply(conn, meta.Addr, &osc.Message{Pattern: p.Address, Arguments: []osc.Argument{v}})
+			return nil
+		}))
+	}
+	return l.Serve(ctx)
+}
+
+// handle applies a message to a parameter, returning its value afterwards and
+// whether to reply with it. Numbers are converted to the parameter's type, as
+// most devices are forgiving about ints and floats.
+func (d *Device) handle(p Param, msg *osc.Message) (osc.Argument, bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(msg.Arguments) == 0 {
+		return d.values[p.Address], true, nil
+	}
+	if len(msg.Arguments) != 1 {
+		return nil, false, fmt.Errorf("expect 1 argument, got: %d", len(msg.Arguments))
+	}
+	var v osc.Argument
+	var err error
+	switch a := msg.Arguments[0].(type) {
+	case *osc.Int32:
+		v, err = p.number(float64(*a))
+	case *osc.Float32:
+		v, err = p.number(float64(*a))
+	case *osc.String:
+		v, err = p.string(string(*a))
+	default:
+		err = fmt.Errorf("expect %s value, got: %v", p.typeName(), a)
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	d.values[p.Address] = v
+	return v, p.Echo, nil
+}
+
+// reply sends a message to addr, after the device's latency.
+func (d *Device) reply(conn net.PacketConn, addr net.Addr, msg *osc.Message) {
+	b := msg.Append(nil)
+	send := func() {
+		if _, err := conn.WriteTo(b, addr); err != nil {
+			log.Printf("%s: error replying to %v: %v", d.desc.Name, addr, err)
+		}
+	}
+	if d.desc.Latency <= 0 {
+		send()
+		return
+	}
+	time.AfterFunc(time.Duration(d.desc.Latency), send)
+}
@@ -0,0 +1,107 @@
+package devicesim
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pfcm/osc"
+)
+
+const desk = `{
+	"name": "desk",
+	"latency": "20ms",
+	"params": [
+		{"address": "/ch/01/mix/fader", "type": "f", "max": 1, "echo": true},
+		{"address": "/ch/01/mix/on", "type": "i", "max": 1, "default": 1},
+		{"address": "/ch/01/config/name", "type": "s", "default": "Kick"}
+	]
+}`
+
+func TestDevice(t *testing.T) {
+	desc, err := ParseDescription(strings.NewReader(desk))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := New(desc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go d.Serve(ctx, conn)
+
+	client, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	// roundTrip sends a message and returns the reply.
+	roundTrip := func(pattern string, args ...osc.Argument) *osc.Message {
+		t.Helper()
+		start := time.Now()
+		if err := osc.Send(client, conn.LocalAddr().String(), pattern, args...); err != nil {
+			t.Fatal(err)
+		}
+		client.SetReadDeadline(time.Now().Add(5 * time.Second))
+		buf := make([]byte, 1024)
+		n, _, err := client.ReadFrom(buf)
+		if err != nil {
+			t.Fatalf("waiting for reply to %s: %v", pattern, err)
+		}
+		if took := time.Since(start); took < 20*time.Millisecond {
+			t.Errorf("reply to %s took %v, want at least the latency", pattern, took)
+		}
+		msg, err := osc.ParseMessage(buf[:n])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return msg
+	}
+
+	for _, c := range []struct {
+		pattern string
+		args    []osc.Argument
+		want    string
+	}{
+		{"/ch/01/config/name", nil, `String("Kick")`},
+		{"/ch/01/mix/on", nil, "Int32(1)"},
+		// Out of range and the wrong type, but the device is forgiving.
+		{"/ch/01/mix/fader", []osc.Argument{osc.AsInt32(2)}, "Float32(1.000000)"},
+	} {
+		msg := roundTrip(c.pattern, c.args...)
+		if msg.Pattern != c.pattern || len(msg.Arguments) != 1 || fmt.Sprint(msg.Arguments[0]) != c.want {
+			t.Errorf("reply to %s %v = %v, want: %s", c.pattern, c.args, msg, c.want)
+		}
+	}
+
+	// Setting a parameter without echo doesn't reply, but does change it.
+	if err := osc.Send(client, conn.LocalAddr().String(), "/ch/01/mix/on", osc.AsInt32(0)); err != nil {
+		t.Fatal(err)
+	}
+	if msg := roundTrip("/ch/01/mix/on"); fmt.Sprint(msg.Arguments[0]) != "Int32(0)" {
+		t.Errorf("/ch/01/mix/on after setting to 0 = %v", msg)
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	for _, desc := range []Description{
+		{Params: []Param{{Address: "/a", Type: "x"}}},
+		{Params: []Param{{Address: "a", Type: "i"}}},
+		{Params: []Param{{Address: "/a", Type: "i", Default: "one"}}},
+		{Params: []Param{{Address: "/a", Type: "s", Default: 1.0}}},
+		{Params: []Param{{Address: "/a", Type: "i"}, {Address: "/a", Type: "f"}}},
+	} {
+		if _, err := New(desc); err == nil {
+			t.Errorf("New(%+v) = nil error, want error", desc)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package osc
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// Difference is one way in which two messages differ.
+type Difference struct {
+	// Field names what differs: "Pattern", "len(Arguments)" or
+	// "Arguments[i]".
+	Field string
+	// A and B are the values from each message. For a missing argument
+	// the value is nil.
+	A, B any
+}
+
+func (d Difference) String() string {
+	return fmt.Sprintf("%s: %v != %v", d.Field, d.A, d.B)
+}
+
+// Diff describes the differences between two messages, or returns nil if they
+// are equal. Arguments are equal if they have the same type and the same
+// canonical encoding (see AppendCanonical), so for example two NaNs are equal.
+// Arguments beyond the end of the shorter message are each reported, after
+// the difference in length.
+func Diff(a, b *Message) []Difference {
+	var diffs []Difference
+	if a.Pattern != b.Pattern {
+		diffs = append(diffs, Difference{"Pattern", a.Pattern, b.Pattern})
+	}
+	if len(a.Arguments) != len(b.Arguments) {
+		diffs = append(diffs, Difference{"len(Arguments)", len(a.Arguments), len(b.Arguments)})
+	}
+	for i := range max(len(a.Arguments), len(b.Arguments)) {
+		var x, y Argument
+		if i < len(a.Arguments) {
+			x = a.Arguments[i]
+		}
+		if i < len(b.Arguments) {
+			y = b.Arguments[i]
+		}
+		if !argumentsEqual(x, y) {
+			diffs = append(diffs, Difference{fmt.Sprintf("Arguments[%d]", i), x, y})
+		}
+	}
+	return diffs
+}
+
+// argumentsEqual reports whether two arguments, either of which may be nil,
+// have the same type and canonical encoding.
+func argumentsEqual(a, b Argument) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	if a.TypeTag() != b.TypeTag() {
+		return false
+	}
+	appendCanonical := func(x Argument) []byte {
+		if c, ok := x.(canonicalAppender); ok {
+			return c.appendCanonical(nil)
+		}
+		return x.Append(nil)
+	}
+	return bytes.Equal(appendCanonical(a), appendCanonical(b))
+}
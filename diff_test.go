@@ -0,0 +1,41 @@
+package osc
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	f := func(v float64) *Float32 {
+		f := Float32(v)
+		return &f
+	}
+	for _, c := range []struct {
+		a, b Message
+		want []string
+	}{{
+		a: Message{Pattern: "/a", Arguments: []Argument{AsInt32(1), AsString("x")}},
+		b: Message{Pattern: "/a", Arguments: []Argument{AsInt32(1), AsString("x")}},
+	}, {
+		// All NaNs are the same.
+		a: Message{Pattern: "/a", Arguments: []Argument{f(math.NaN()), f(0)}},
+		b: Message{Pattern: "/a", Arguments: []Argument{f(-math.NaN()), f(math.Copysign(0, -1))}},
+	}, {
+		a:    Message{Pattern: "/a", Arguments: []Argument{AsInt32(1), True{}}},
+		b:    Message{Pattern: "/b", Arguments: []Argument{f(1), True{}}},
+		want: []string{"Pattern: /a != /b", "Arguments[0]: Int32(1) != Float32(1.000000)"},
+	}, {
+		a:    Message{Pattern: "/a", Arguments: []Argument{AsInt32(1)}},
+		b:    Message{Pattern: "/a", Arguments: []Argument{AsInt32(2), Null{}, False{}}},
+		want: []string{"len(Arguments): 1 != 3", "Arguments[0]: Int32(1) != Int32(2)", "Arguments[1]: <nil> != Null", "Arguments[2]: <nil> != False"},
+	}} {
+		var got []string
+		for _, d := range Diff(&c.a, &c.b) {
+			got = append(got, d.String())
+		}
+		if !slices.Equal(got, c.want) {
+			t.Errorf("Diff(%v, %v) = %q, want: %q", c.a, c.b, got, c.want)
+		}
+	}
+}
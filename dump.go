@@ -0,0 +1,76 @@
+package osc
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// dumpWidth is the number of bytes on each line of an AnnotatedDump.
+const dumpWidth = 8
+
+// AnnotatedDump returns a hexdump of the message's encoding, in the style of
+// Wireshark, with each part of the message labelled:
+//
+//	0000  2f 74 65 73 74 00 00 00  /test...  address "/test"
+//	0008  2c 69 73 00              ,is.      type tags ",is"
+//	000c  00 00 00 0c              ....      Int32(12)
+//	0010  68 69 00 00              hi..      String("hi")
+//
+// It's meant for comparing against packet captures.
+func (m Message) AnnotatedDump() string {
+	var sb strings.Builder
+	off := 0
+	field := func(b []byte, label string) {
+		for i := 0; i == 0 || i < len(b); i += dumpWidth {
+			row := b[i:min(i+dumpWidth, len(b))]
+			line := fmt.Sprintf("%04x  %-*s  %-*s", off+i, dumpWidth*3-1, hexBytes(row), dumpWidth, printable(row))
+			if i == 0 {
+				line += "  " + label
+			}
+			sb.WriteString(strings.TrimRight(line, " ") + "\n")
+		}
+		off += len(b)
+	}
+	field(m.pattern().Append(nil), fmt.Sprintf("address %q", string(m.pattern())))
+	tags := make([]byte, 0, len(m.Arguments)+1)
+	tags = append(tags, ',')
+	for _, a := range m.Arguments {
+		tags = utf8.AppendRune(tags, a.TypeTag())
+	}
+	field(String(tags).Append(nil), fmt.Sprintf("type tags %q", tags))
+	for _, a := range m.Arguments {
+		b := a.Append(nil)
+		if len(b) == 0 {
+			// Arguments only in the type tags still get a line.
+			fmt.Fprintf(&sb, "%04x  %-*s  %-*s  %v\n", off, dumpWidth*3-1, "", dumpWidth, "", a)
+			continue
+		}
+		field(b, fmt.Sprint(a))
+	}
+	return sb.String()
+}
+
+// hexBytes formats b as space separated hex bytes.
+func hexBytes(b []byte) string {
+	var sb strings.Builder
+	for i, c := range b {
+		if i > 0 {
+			sb.WriteByte(' ')
+		}
+		fmt.Fprintf(&sb, "%02x", c)
+	}
+	return sb.String()
+}
+
+// printable returns b with anything but printable ASCII replaced by dots.
+func printable(b []byte) string {
+	p := make([]byte, len(b))
+	for i, c := range b {
+		p[i] = '.'
+		if c >= 0x20 && c < 0x7f {
+			p[i] = c
+		}
+	}
+	return string(p)
+}
@@ -0,0 +1,21 @@
+package osc
+
+import "testing"
+
+func TestAnnotatedDump(t *testing.T) {
+	msg := Message{
+		Pattern:   "/test",
+		Arguments: []Argument{AsInt32(12), AsString("hi"), True{}, &Blob{1, 2, 3, 4, 5, 6, 7, 8, 9}},
+	}
+	want := `0000  2f 74 65 73 74 00 00 00  /test...  address "/test"
+0008  2c 69 73 54 62 00 00 00  ,isTb...  type tags ",isTb"
+0010  00 00 00 0c              ....      Int32(12)
+0014  68 69 00 00              hi..      String("hi")
+0018                                     True
+0018  00 00 00 09 01 02 03 04  ........  Blob(010203040506070809)
+0020  05 06 07 08 09 00 00 00  ........
+`
+	if got := msg.AnnotatedDump(); got != want {
+		t.Errorf("AnnotatedDump() =\n%s\nwant:\n%s", got, want)
+	}
+}
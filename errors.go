@@ -0,0 +1,36 @@
+package osc
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Errors returned when parsing messages. They're wrapped with more detail, so
+// check for them with errors.Is and errors.As.
+var (
+	// ErrShortBuffer means a value was cut off by the end of the packet.
+	ErrShortBuffer = errors.New("osc: short buffer")
+	// ErrUnterminatedString means a string had no terminating zero.
+	ErrUnterminatedString = errors.New("osc: unterminated string")
+	// ErrBadAddress means a message's address pattern couldn't be read,
+	// or from Message.Validate, isn't valid.
+	ErrBadAddress = errors.New("osc: bad address")
+	// ErrEmptyPacket means there was nothing to parse.
+	ErrEmptyPacket = errors.New("osc: empty packet")
+)
+
+// ErrBadTypeTag means a message's type tags couldn't be parsed, either because
+// the type tag string doesn't start with a ',' or because it contains a tag
+// with no registered type.
+type ErrBadTypeTag struct {
+	// Tag is the unknown tag, or 0 if the type tag string is missing its
+	// ','.
+	Tag rune
+}
+
+func (e ErrBadTypeTag) Error() string {
+	if e.Tag == 0 {
+		return "osc: type tag string must start with ','"
+	}
+	return fmt.Sprintf("osc: unknown type tag %q", e.Tag)
+}
@@ -0,0 +1,40 @@
+package osc
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseErrors(t *testing.T) {
+	for _, c := range []struct {
+		packet []byte
+		want   error
+	}{
+		{nil, ErrEmptyPacket},
+		{[]byte{}, ErrEmptyPacket},
+		{[]byte("/abc"), ErrUnterminatedString},
+		{[]byte("/abc"), ErrBadAddress},
+		{[]byte("/a\x00\x00,s\x00\x00abc"), ErrUnterminatedString},
+		{[]byte("/a\x00\x00,i\x00\x00\x00\x01"), ErrShortBuffer},
+		{[]byte("/a\x00\x00,h\x00\x00\x00\x00\x00\x01"), ErrShortBuffer},
+		{[]byte("/a\x00\x00,f\x00\x00"), ErrShortBuffer},
+		{[]byte("/a\x00\x00,c\x00\x00"), ErrShortBuffer},
+		{[]byte("/a\x00\x00,r\x00\x00\xff\x00"), ErrShortBuffer},
+		{[]byte("/a\x00\x00,m\x00\x00\x01\x90\x3c"), ErrShortBuffer},
+		{[]byte("/a\x00\x00,d\x00\x00\x00\x00\x00\x01"), ErrShortBuffer},
+		{[]byte("/a\x00\x00,t\x00\x00\x00\x00\x00\x01"), ErrShortBuffer},
+		{[]byte("/a\x00\x00,b\x00\x00\x00\x00\x00\x08abcd"), ErrShortBuffer},
+		{[]byte("/a\x00\x00,q\x00\x00"), ErrBadTypeTag{Tag: 'q'}},
+		{[]byte("/a\x00\x00i\x00\x00\x00"), ErrBadTypeTag{}},
+	} {
+		_, err := ParseMessage(c.packet)
+		if !errors.Is(err, c.want) {
+			t.Errorf("ParseMessage(%q) = %v, want: %v", c.packet, err, c.want)
+		}
+	}
+
+	var bad ErrBadTypeTag
+	if _, err := ParseMessage([]byte("/a\x00\x00,?\x00\x00")); !errors.As(err, &bad) || bad.Tag != '?' {
+		t.Errorf("ParseMessage with unknown type tag = %v, want ErrBadTypeTag{'?'}", err)
+	}
+}
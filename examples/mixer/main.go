@@ -0,0 +1,80 @@
+// mixer is a mock of a small digital mixing desk, with a fader and a mute for
+// each channel at /ch/N/fader (float) and /ch/N/mute (int).
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"sync"
+
+	"github.com/pfcm/osc"
+	"github.com/pfcm/osc/server"
+)
+
+var (
+	listenAddrFlag = flag.String("listen_addr", "127.0.0.1:10023", "`host:port`: the address to listen on.")
+	channelsFlag   = flag.Int("channels", 16, "`number` of channels on the desk")
+)
+
+func main() {
+	flag.Parse()
+
+	conn, err := net.ListenPacket("udp", *listenAddrFlag)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("Mixer with %d channels listening on %v", *channelsFlag, conn.LocalAddr())
+	l := server.NewListener(conn, 1)
+	newMixer(*channelsFlag).register(l)
+	log.Fatal(l.Serve(context.Background()))
+}
+
+type mixer struct {
+	mu     sync.Mutex
+	faders []float32
+	mutes  []bool
+}
+
+func newMixer(channels int) *mixer {
+	return &mixer{
+		faders: make([]float32, channels),
+		mutes:  make([]bool, channels),
+	}
+}
+
+// register adds handlers for all of the mixer's controls.
+func (m *mixer) register(l *server.Listener) {
+	for i := range m.faders {
+		l.Handle(fmt.Sprintf("/ch/%d/fader", i+1), server.HandlerFunc(func(msg *osc.Message) error {
+			if err := msg.CheckTypes("f"); err != nil {
+				return err
+			}
+			v := float32(*msg.Arguments[0].(*osc.Float32))
+			m.mu.Lock()
+			defer m.mu.Unlock()
+			m.faders[i] = min(max(v, 0), 1)
+			log.Printf("Channel %d: fader %.2f", i+1, m.faders[i])
+			return nil
+		}))
+		l.Handle(fmt.Sprintf("/ch/%d/mute", i+1), server.HandlerFunc(func(msg *osc.Message) error {
+			if err := msg.CheckTypes("i"); err != nil {
+				return err
+			}
+			m.mu.Lock()
+			defer m.mu.Unlock()
+			m.mutes[i] = *msg.Arguments[0].(*osc.Int32) != 0
+			log.Printf("Channel %d: mute %t", i+1, m.mutes[i])
+			return nil
+		}))
+	}
+}
+
+// channel returns the state of a channel, numbered from 1.
+func (m *mixer) channel(n int) (fader float32, mute bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.faders[n-1], m.mutes[n-1]
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/pfcm/osc"
+	"github.com/pfcm/osc/server"
+)
+
+func TestMixer(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	m := newMixer(4)
+	l := server.NewListener(conn, 1)
+	m.register(l)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go l.Serve(ctx)
+
+	f := osc.Float32(0.5)
+	if err := osc.Send(conn, conn.LocalAddr().String(), "/ch/2/fader", &f); err != nil {
+		t.Fatal(err)
+	}
+	if err := osc.Send(conn, conn.LocalAddr().String(), "/ch/3/mute", osc.AsInt32(1)); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		fader, _ := m.channel(2)
+		_, mute := m.channel(3)
+		if fader == 0.5 && mute {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Error("mixer state not updated")
+}
@@ -0,0 +1,72 @@
+// supercollider plays an arpeggio on a SuperCollider server (scsynth), using
+// the default synth.
+package main
+
+import (
+	"flag"
+	"log"
+	"math"
+	"net"
+	"time"
+
+	"github.com/pfcm/osc"
+)
+
+var (
+	sendAddrFlag = flag.String("send_addr", "127.0.0.1:57110", "`host:port`: the address of scsynth.")
+	noteFlag     = flag.Duration("note", 250*time.Millisecond, "`duration` of each note")
+)
+
+func main() {
+	flag.Parse()
+
+	conn, err := net.ListenPacket("udp", "0.0.0.0:0")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer conn.Close()
+
+	// A minor seventh chord, as MIDI notes.
+	for i, n := range []int{57, 60, 64, 67, 69, 67, 64, 60} {
+		id := int32(1000 + i)
+		on := noteOn(id, midiToFreq(n))
+		if err := osc.Send(conn, *sendAddrFlag, on.Pattern, on.Arguments...); err != nil {
+			log.Fatal(err)
+		}
+		time.Sleep(*noteFlag)
+		off := noteOff(id)
+		if err := osc.Send(conn, *sendAddrFlag, off.Pattern, off.Arguments...); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+// noteOn starts a new instance of the default synth with the given node id.
+func noteOn(id int32, freq float32) *osc.Message {
+	f := osc.Float32(freq)
+	return &osc.Message{
+		Pattern: "/s_new",
+		Arguments: []osc.Argument{
+			osc.AsString("default"),
+			osc.AsInt32(id),
+			osc.AsInt32(0), // add to the head
+			osc.AsInt32(1), // of the default group
+			osc.AsString("freq"), &f,
+		},
+	}
+}
+
+// noteOff releases the synth with the given node id.
+func noteOff(id int32) *osc.Message {
+	return &osc.Message{
+		Pattern: "/n_set",
+		Arguments: []osc.Argument{
+			osc.AsInt32(id),
+			osc.AsString("gate"), osc.AsInt32(0),
+		},
+	}
+}
+
+func midiToFreq(n int) float32 {
+	return float32(440 * math.Pow(2, float64(n-69)/12))
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/pfcm/osc"
+)
+
+func TestMessages(t *testing.T) {
+	for _, c := range []struct {
+		msg *osc.Message
+		tt  string
+	}{
+		{noteOn(1, 440), "siiisf"},
+		{noteOff(1), "isi"},
+	} {
+		if err := c.msg.CheckTypes(c.tt); err != nil {
+			t.Errorf("%v: %v", c.msg, err)
+		}
+		if _, err := osc.ParseMessage(c.msg.Append(nil)); err != nil {
+			t.Errorf("%v does not survive encoding: %v", c.msg, err)
+		}
+	}
+	if f := midiToFreq(69); f != 440 {
+		t.Errorf("midiToFreq(69) = %f, want 440", f)
+	}
+}
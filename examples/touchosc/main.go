@@ -0,0 +1,76 @@
+// touchosc bridges the default layout of TouchOSC to the mixer example: the
+// faders and toggles on the first page control the fader and mute of the
+// matching channel.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+
+	"github.com/pfcm/osc"
+	"github.com/pfcm/osc/server"
+)
+
+var (
+	listenAddrFlag = flag.String("listen_addr", "0.0.0.0:8000", "`host:port`: the address to listen for TouchOSC on.")
+	sendAddrFlag   = flag.String("send_addr", "127.0.0.1:10023", "`host:port`: the address of the mixer.")
+)
+
+const (
+	faders  = 5
+	toggles = 4
+)
+
+func main() {
+	flag.Parse()
+
+	conn, err := net.ListenPacket("udp", *listenAddrFlag)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("Bridging TouchOSC on %v to %v", conn.LocalAddr(), *sendAddrFlag)
+	l := server.NewListener(conn, 1)
+	forward := server.HandlerFunc(func(msg *osc.Message) error {
+		out, err := translate(msg)
+		if err != nil {
+			return err
+		}
+		return osc.Send(conn, *sendAddrFlag, out.Pattern, out.Arguments...)
+	})
+	for i := range faders {
+		l.Handle(fmt.Sprintf("/1/fader%d", i+1), forward)
+	}
+	for i := range toggles {
+		l.Handle(fmt.Sprintf("/1/toggle%d", i+1), forward)
+	}
+	log.Fatal(l.Serve(context.Background()))
+}
+
+// translate maps a message from TouchOSC onto the mixer's addresses.
+func translate(msg *osc.Message) (*osc.Message, error) {
+	if err := msg.CheckTypes("f"); err != nil {
+		return nil, err
+	}
+	v := *msg.Arguments[0].(*osc.Float32)
+	var n int
+	if _, err := fmt.Sscanf(msg.Pattern, "/1/fader%d", &n); err == nil {
+		return &osc.Message{
+			Pattern:   fmt.Sprintf("/ch/%d/fader", n),
+			Arguments: []osc.Argument{&v},
+		}, nil
+	}
+	if _, err := fmt.Sscanf(msg.Pattern, "/1/toggle%d", &n); err == nil {
+		mute := 0
+		if v > 0.5 {
+			mute = 1
+		}
+		return &osc.Message{
+			Pattern:   fmt.Sprintf("/ch/%d/mute", n),
+			Arguments: []osc.Argument{osc.AsInt32(mute)},
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown control %q", msg.Pattern)
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/pfcm/osc"
+)
+
+func TestTranslate(t *testing.T) {
+	f := func(f float32) *osc.Float32 {
+		ff := osc.Float32(f)
+		return &ff
+	}
+	for _, c := range []struct {
+		in   *osc.Message
+		want *osc.Message
+	}{{
+		in:   &osc.Message{Pattern: "/1/fader3", Arguments: []osc.Argument{f(0.25)}},
+		want: &osc.Message{Pattern: "/ch/3/fader", Arguments: []osc.Argument{f(0.25)}},
+	}, {
+		in:   &osc.Message{Pattern: "/1/toggle1", Arguments: []osc.Argument{f(1)}},
+		want: &osc.Message{Pattern: "/ch/1/mute", Arguments: []osc.Argument{osc.AsInt32(1)}},
+	}, {
+		in:   &osc.Message{Pattern: "/1/toggle2", Arguments: []osc.Argument{f(0)}},
+		want: &osc.Message{Pattern: "/ch/2/mute", Arguments: []osc.Argument{osc.AsInt32(0)}},
+	}, {
+		in: &osc.Message{Pattern: "/2/push1", Arguments: []osc.Argument{f(1)}},
+	}, {
+		in: &osc.Message{Pattern: "/1/fader1", Arguments: []osc.Argument{osc.AsInt32(1)}},
+	}} {
+		got, err := translate(c.in)
+		if err != nil {
+			if c.want != nil {
+				t.Errorf("translate(%v): %v", c.in, err)
+			}
+			continue
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("translate(%v) = %v, want: %v", c.in, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package osc
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxExpansions limits the number of addresses Expand and ExpandBraces
+// return, so a mistyped pattern can't use all the memory.
+const maxExpansions = 1 << 16
+
+// ExpandBraces returns the addresses matched by an address with "{a,b}"
+// alternatives, such as "/ch/{1,2,5}/mute", for sending to servers that don't
+// support patterns. Other pattern characters are left as they are.
+func ExpandBraces(address string) ([]string, error) {
+	return expand(address, false)
+}
+
+// Expand is like ExpandBraces, but also expands character classes such as
+// "[1-8]" or "[abc]". Addresses with "*", "?" or a negated class match
+// infinitely many, or nonsensical, addresses so are an error.
+func Expand(address string) ([]string, error) {
+	return expand(address, true)
+}
+
+func expand(address string, classes bool) ([]string, error) {
+	out := []string{""}
+	for len(address) > 0 {
+		i := strings.IndexAny(address, "{[*?")
+		if i < 0 {
+			i = len(address)
+		}
+		for j := range out {
+			out[j] += address[:i]
+		}
+		address = address[i:]
+		if len(address) == 0 {
+			break
+		}
+		var alts []string
+		switch c := address[0]; {
+		case c == '{':
+			end := strings.IndexByte(address, '}')
+			if end < 0 {
+				return nil, fmt.Errorf("expect %q somewhere, got: %q", "}", address)
+			}
+			alts = strings.Split(address[1:end], ",")
+			address = address[end+1:]
+		case !classes:
+			for j := range out {
+				out[j] += address[:1]
+			}
+			address = address[1:]
+			continue
+		case c == '[':
+			end := strings.IndexByte(address, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("expect %q somewhere, got: %q", "]", address)
+			}
+			var err error
+			if alts, err = classChars(address[1:end]); err != nil {
+				return nil, err
+			}
+			address = address[end+1:]
+		default:
+			return nil, fmt.Errorf("can't expand wildcard %q", c)
+		}
+		if len(out)*len(alts) > maxExpansions {
+			return nil, fmt.Errorf("expands to more than %d addresses", maxExpansions)
+		}
+		next := make([]string, 0, len(out)*len(alts))
+		for _, o := range out {
+			for _, a := range alts {
+				next = append(next, o+a)
+			}
+		}
+		out = next
+	}
+	return out, nil
+}
+
+// classChars returns each character in a character class, the part of a
+// pattern between "[" and "]".
+func classChars(class string) ([]string, error) {
+	if strings.HasPrefix(class, "!") {
+		return nil, fmt.Errorf("can't expand negated class %q", "["+class+"]")
+	}
+	var chars []string
+	for i := 0; i < len(class); i++ {
+		// A - is a range, unless it's at either end.
+		if i+2 < len(class) && class[i+1] == '-' {
+			lo, hi := class[i], class[i+2]
+			if hi < lo {
+				return nil, fmt.Errorf("invalid range %c-%c, %c<%c", lo, hi, hi, lo)
+			}
+			for c := int(lo); c <= int(hi); c++ {
+				chars = append(chars, string(rune(c)))
+			}
+			i += 2
+			continue
+		}
+		chars = append(chars, class[i:i+1])
+	}
+	return chars, nil
+}
@@ -0,0 +1,40 @@
+package osc
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestExpand(t *testing.T) {
+	for _, c := range []struct {
+		address string
+		braces  []string
+		all     []string
+	}{
+		{"/ch/1/mute", []string{"/ch/1/mute"}, []string{"/ch/1/mute"}},
+		{"/ch/{1,2,5}/mute", []string{"/ch/1/mute", "/ch/2/mute", "/ch/5/mute"}, []string{"/ch/1/mute", "/ch/2/mute", "/ch/5/mute"}},
+		{"/{a,b}/{x,}", []string{"/a/x", "/a/", "/b/x", "/b/"}, []string{"/a/x", "/a/", "/b/x", "/b/"}},
+		{"/ch/[1-3]/mute", []string{"/ch/[1-3]/mute"}, []string{"/ch/1/mute", "/ch/2/mute", "/ch/3/mute"}},
+		{"/[ab-]/{x,y}", []string{"/[ab-]/x", "/[ab-]/y"}, []string{"/a/x", "/a/y", "/b/x", "/b/y", "/-/x", "/-/y"}},
+		{"/ch/*", []string{"/ch/*"}, nil},
+		{"/ch/?", []string{"/ch/?"}, nil},
+		{"/ch/[!1]", []string{"/ch/[!1]"}, nil},
+		{"/ch/[3-1]", []string{"/ch/[3-1]"}, nil},
+		{"/ch/[1", []string{"/ch/[1"}, nil},
+		{"/ch/{1", nil, nil},
+	} {
+		got, err := ExpandBraces(c.address)
+		if (err != nil) != (c.braces == nil) || !slices.Equal(got, c.braces) {
+			t.Errorf("ExpandBraces(%q) = %q, %v, want: %q", c.address, got, err, c.braces)
+		}
+		got, err = Expand(c.address)
+		if (err != nil) != (c.all == nil) || !slices.Equal(got, c.all) {
+			t.Errorf("Expand(%q) = %q, %v, want: %q", c.address, got, err, c.all)
+		}
+	}
+
+	if _, err := Expand(strings.Repeat("/[0-9]", 5)); err == nil {
+		t.Error("Expand(100000 addresses) = nil error, want error")
+	}
+}
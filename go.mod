@@ -1,8 +1,3 @@
 module github.com/pfcm/osc
 
 go 1.23
-
-require (
-	golang.org/x/exp v0.0.0-20240823005443-9b4947da3948
-	golang.org/x/sync v0.8.0
-)
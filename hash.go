@@ -0,0 +1,31 @@
+package osc
+
+import "hash/maphash"
+
+// Hash64 returns a 64-bit FNV-1a hash of the message's canonical encoding (see
+// AppendCanonical), so messages with equal values have equal hashes. The hash
+// is the same in every process, so it can be stored or shared, but peers can
+// choose messages that collide; use HashSeed where that matters.
+func (m Message) Hash64() uint64 {
+	b := m.AppendCanonical(getBuf())
+	defer putBuf(b)
+	const (
+		offset = 14695981039346656037
+		prime  = 1099511628211
+	)
+	h := uint64(offset)
+	for _, c := range b {
+		h ^= uint64(c)
+		h *= prime
+	}
+	return h
+}
+
+// HashSeed returns a keyed hash of the message's canonical encoding. Hashes
+// with different seeds are unrelated, so without the seed it's impractical to
+// find messages that collide.
+func (m Message) HashSeed(seed maphash.Seed) uint64 {
+	b := m.AppendCanonical(getBuf())
+	defer putBuf(b)
+	return maphash.Bytes(seed, b)
+}
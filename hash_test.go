@@ -0,0 +1,39 @@
+package osc
+
+import (
+	"hash/maphash"
+	"math"
+	"testing"
+)
+
+func TestHash(t *testing.T) {
+	zero, negZero := Float32(0), Float32(math.Copysign(0, -1))
+	a := &Message{Pattern: "/hash", Arguments: []Argument{AsInt32(1), &zero}}
+	b := &Message{Pattern: "/hash", Arguments: []Argument{AsInt32(1), &negZero}}
+	different := []*Message{
+		{Pattern: "/hash", Arguments: []Argument{AsInt32(2), &zero}},
+		{Pattern: "/hash2", Arguments: []Argument{AsInt32(1), &zero}},
+		{Pattern: "/hash", Arguments: []Argument{AsInt32(1)}},
+	}
+
+	seed := maphash.MakeSeed()
+	if a.Hash64() != b.Hash64() {
+		t.Errorf("Hash64 differs for equal messages %v and %v", a, b)
+	}
+	if a.HashSeed(seed) != b.HashSeed(seed) {
+		t.Errorf("HashSeed differs for equal messages %v and %v", a, b)
+	}
+	for _, d := range different {
+		if a.Hash64() == d.Hash64() {
+			t.Errorf("Hash64 of %v and %v collide", a, d)
+		}
+		if a.HashSeed(seed) == d.HashSeed(seed) {
+			t.Errorf("HashSeed of %v and %v collide", a, d)
+		}
+	}
+	// Hash64 must be stable, check it against a known value.
+	const want uint64 = 0xab4e510035618929
+	if got := (&Message{Pattern: "/a"}).Hash64(); got != want {
+		t.Errorf("Hash64 of /a = %#x, want %#x", got, want)
+	}
+}
@@ -0,0 +1,69 @@
+//go:build !tinygo
+
+package osc
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+)
+
+// HelloAddress is the address of the handshake message exchanged by peers on
+// a stream transport when they connect, see Hello.
+const HelloAddress = "/sys/hello"
+
+// Hello negotiates optional features with the peer at addr over conn, usually
+// a newly connected stream transport such as a wsconn or pipeconn. Each side
+// sends a message to HelloAddress with a String argument for each of its
+// capabilities, then waits for the other's, so the same call is made at both
+// ends. The first packet received must be the peer's hello. Hello returns the
+// capabilities both peers support, which the application can consult before
+// using an optional feature.
+//
+// The context's deadline is used as the deadline of conn for the whole
+// exchange.
+func Hello(ctx context.Context, conn net.PacketConn, addr net.Addr, local Capabilities) (Capabilities, error) {
+	if ctx.Done() != nil {
+		if d, ok := ctx.Deadline(); ok {
+			conn.SetDeadline(d)
+		}
+		defer conn.SetDeadline(time.Time{})
+		stop := context.AfterFunc(ctx, func() {
+			conn.SetDeadline(time.Now())
+		})
+		defer stop()
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if _, err := conn.WriteTo(local.message(HelloAddress).Append(nil), addr); err != nil {
+		return nil, helloErr(ctx, err)
+	}
+	buf := make([]byte, 1<<16)
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		return nil, helloErr(ctx, err)
+	}
+	msg, err := ParseMessage(buf[:n])
+	if err != nil {
+		return nil, fmt.Errorf("parsing hello: %w", err)
+	}
+	remote, err := parseCapabilities(msg, HelloAddress)
+	if err != nil {
+		return nil, err
+	}
+	return local.Intersect(remote), nil
+}
+
+// helloErr returns the context's error if it caused err. The conn's deadline
+// may pass just before the context notices its own.
+func helloErr(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if d, ok := ctx.Deadline(); ok && !time.Now().Before(d) {
+		return context.DeadlineExceeded
+	}
+	return err
+}
@@ -0,0 +1,64 @@
+//go:build !tinygo
+
+package osc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestHello(t *testing.T) {
+	a, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	b, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	type result struct {
+		caps Capabilities
+		err  error
+	}
+	done := make(chan result)
+	go func() {
+		caps, err := Hello(ctx, b, a.LocalAddr(), Capabilities{CapGzip: true, CapTCP: true})
+		done <- result{caps, err}
+	}()
+	got, err := Hello(ctx, a, b.LocalAddr(), Capabilities{CapGzip: true, CapSLIP: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := <-done
+	if other.err != nil {
+		t.Fatal(other.err)
+	}
+	want := Capabilities{CapGzip: true}
+	if !reflect.DeepEqual(got, want) || !reflect.DeepEqual(other.caps, want) {
+		t.Errorf("Hello negotiated %v and %v, want: %v", got, other.caps, want)
+	}
+
+	// Anything other than a hello first is an error.
+	go Send(b, a.LocalAddr().String(), "/other")
+	if _, err := Hello(ctx, a, b.LocalAddr(), nil); err == nil {
+		t.Error("Hello with a non-hello reply = nil, want error")
+	}
+	// Drain the hello sent to b.
+	b.SetReadDeadline(time.Now().Add(5 * time.Second))
+	b.ReadFrom(make([]byte, 1024))
+
+	short, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := Hello(short, a, b.LocalAddr(), nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Hello with no peer = %v, want: %v", err, context.DeadlineExceeded)
+	}
+}
@@ -0,0 +1,45 @@
+package osc
+
+import "sync"
+
+// Interner deduplicates strings that are received over and over, such as the
+// address of every "/ping" or enum-like string arguments, so parsing them
+// doesn't allocate a new copy each time. It holds at most a fixed number of
+// strings, the first distinct ones it sees: once it's full, any others are
+// allocated as usual. It's safe for concurrent use.
+type Interner struct {
+	mu   sync.RWMutex
+	max  int
+	strs map[string]string
+}
+
+// NewInterner returns an Interner holding up to max strings.
+func NewInterner(max int) *Interner {
+	return &Interner{max: max, strs: make(map[string]string)}
+}
+
+// Intern returns b as a string, the same one as earlier calls with the same
+// bytes if it's held.
+func (in *Interner) Intern(b []byte) string {
+	in.mu.RLock()
+	// This lookup doesn't allocate.
+	s, ok := in.strs[string(b)]
+	in.mu.RUnlock()
+	if ok {
+		return s
+	}
+	s = string(b)
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if len(in.strs) < in.max {
+		in.strs[s] = s
+	}
+	return s
+}
+
+// Len returns the number of strings held.
+func (in *Interner) Len() int {
+	in.mu.RLock()
+	defer in.mu.RUnlock()
+	return len(in.strs)
+}
@@ -0,0 +1,51 @@
+package osc
+
+import (
+	"testing"
+	"unsafe"
+)
+
+func TestInterner(t *testing.T) {
+	in := NewInterner(2)
+	a, b := in.Intern([]byte("/ping")), in.Intern([]byte("/ping"))
+	if a != "/ping" || unsafe.StringData(a) != unsafe.StringData(b) {
+		t.Errorf("Intern(/ping) twice returned different strings")
+	}
+	in.Intern([]byte("on"))
+	// Full, so this isn't held.
+	if c, d := in.Intern([]byte("off")), in.Intern([]byte("off")); c != "off" || unsafe.StringData(c) == unsafe.StringData(d) {
+		t.Errorf("Intern(off) on a full Interner returned the same string twice")
+	}
+	if got := in.Len(); got != 2 {
+		t.Errorf("Len() = %d, want: 2", got)
+	}
+}
+
+func TestCodecInterning(t *testing.T) {
+	packets := [][]byte{
+		(&Message{Pattern: "/ping", Arguments: []Argument{AsString("on")}}).Append(nil),
+		(&Message{Pattern: "/pong", Arguments: []Argument{AsString("off")}}).Append(nil),
+	}
+	c := new(Codec).WithInterner(NewInterner(100))
+	var m Message
+	i := 0
+	parse := func() {
+		if err := c.ParseMessageInto(&m, packets[i%2]); err != nil {
+			t.Fatal(err)
+		}
+		i++
+	}
+	parse()
+	parse()
+	if allocs := testing.AllocsPerRun(100, parse); allocs != 0 {
+		t.Errorf("parsing repeated strings allocated %.1f times, want: 0", allocs)
+	}
+	if got := *m.Arguments[0].(*String); m.Pattern == "/ping" && got != "on" || m.Pattern == "/pong" && got != "off" {
+		t.Errorf("parsed %v, want: /ping on or /pong off", m)
+	}
+	// Without interning the strings are allocated each time.
+	c = new(Codec)
+	if allocs := testing.AllocsPerRun(100, parse); allocs < 2 {
+		t.Errorf("parsing without interning allocated %.1f times, want at least 2", allocs)
+	}
+}
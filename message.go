@@ -5,7 +5,10 @@ import (
 	"encoding/binary"
 	"fmt"
 	"math"
+	"slices"
+	"strings"
 	"time"
+	"unicode/utf8"
 )
 
 // Message represents an OSC message.
@@ -16,64 +19,155 @@ type Message struct {
 	Arguments []Argument
 }
 
-// ParseMessage parses a message.
+// ParseMessage parses a message, understanding the standard argument types and
+// any added with RegisterType.
 func ParseMessage(buf []byte) (*Message, error) {
-	// A message begins with the address, which is a string.
-	var addr String
-	buf, err := addr.Consume(buf)
-	if err != nil {
-		return nil, fmt.Errorf("reading address pattern: %w", err)
-	}
-	// Next is the type tag string.
-	var tt String
-	buf, err = tt.Consume(buf)
-	if err != nil {
-		return nil, fmt.Errorf("reading type tag: %w", err)
+	return defaultCodec.ParseMessage(buf)
+}
+
+// ParseMessageInto parses a message into m, reusing its memory where possible,
+// see Codec.ParseMessageInto.
+func ParseMessageInto(m *Message, buf []byte) error {
+	return defaultCodec.ParseMessageInto(m, buf)
+}
+
+// Reset clears the message so it can be reused, for example by
+// ParseMessageInto. The old arguments are kept beyond the length of
+// m.Arguments, so they can be reused too.
+func (m *Message) Reset() {
+	m.Pattern = ""
+	m.Arguments = m.Arguments[:0]
+}
+
+// IsZero reports whether the message has no pattern and no arguments.
+func (m Message) IsZero() bool {
+	return m.Pattern == "" && len(m.Arguments) == 0
+}
+
+// Validate checks the message can be encoded and understood by other
+// implementations: the pattern must start with a "/" and, like strings, can't
+// contain zero bytes, and no argument can be nil.
+func (m Message) Validate() error {
+	if !strings.HasPrefix(m.Pattern, "/") {
+		return fmt.Errorf("%w: expect pattern starting with /, got: %q", ErrBadAddress, m.Pattern)
 	}
-	if len(tt) == 0 || tt[0] != ',' {
-		// TODO: the spec talks about handling this case, but it is
-		// unclear how.
-		return nil, fmt.Errorf("invalid type tag string: %q", tt)
+	if strings.IndexByte(m.Pattern, 0) >= 0 {
+		return fmt.Errorf("%w: pattern %q contains a zero byte", ErrBadAddress, m.Pattern)
 	}
-	args := make([]Argument, len(tt)-1)
-	for i, t := range tt[1:] {
-		c, ok := newByTypeTag[t]
-		if !ok {
-			return nil, fmt.Errorf("unknown type tag %c", t)
+	for i, a := range m.Arguments {
+		switch a := a.(type) {
+		case nil:
+			return fmt.Errorf("argument %d is nil", i)
+		case *String:
+			if strings.IndexByte(string(*a), 0) >= 0 {
+				return fmt.Errorf("argument %d: string %q contains a zero byte", i, *a)
+			}
+		case *Symbol:
+			if strings.IndexByte(string(*a), 0) >= 0 {
+				return fmt.Errorf("argument %d: symbol %q contains a zero byte", i, *a)
+			}
 		}
-		a := c()
-		buf, err = a.Consume(buf)
-		if err != nil {
-			return nil, fmt.Errorf("reading argument %d (%c): %w", i, t, err)
+	}
+	return nil
+}
+
+// Append encodes the message and appends it to the provided slice. The
+// encoding is deterministic: messages with the same pattern and arguments
+// always encode to the same bytes. Values are encoded exactly as they are, so
+// for example the payload bits of a NaN are preserved; see AppendCanonical to
+// normalise them.
+//
+// The message isn't checked, see Validate, but an empty pattern is encoded as
+// "/", so even a zero Message gives a valid packet: "/" with no arguments.
+func (m Message) Append(b []byte) []byte {
+	return m.append(b, false)
+}
+
+// AppendCanonical is like Append, but first normalises values that have more
+// than one encoding: negative zero is encoded as positive zero, and every NaN
+// as the same quiet NaN. Messages with equal values have identical canonical
+// encodings, which makes them suitable for hashing or as keys.
+func (m Message) AppendCanonical(b []byte) []byte {
+	return m.append(b, true)
+}
+
+// canonicalAppender is implemented by arguments with more than one encoding
+// for the same value.
+type canonicalAppender interface {
+	appendCanonical([]byte) []byte
+}
+
+// EncodedSize returns the size of the message's encoding, for checking it
+// against a limit such as the network's MTU before sending, or allocating a
+// buffer of exactly the right size. Arguments that aren't Sizers, such as
+// Compressed, are encoded to find their size.
+func (m Message) EncodedSize() int {
+	if n, ok := m.encodedSize(); ok {
+		return n
+	}
+	n := m.pattern().EncodedSize()
+	tags := 2 // The ',' and the terminating 0.
+	for _, a := range m.Arguments {
+		tags += utf8.RuneLen(a.TypeTag())
+		if s, ok := a.(Sizer); ok {
+			n += s.EncodedSize()
+		} else {
+			n += len(a.Append(nil))
 		}
-		args[i] = a
 	}
+	return n + paddedSize(tags)
+}
 
-	return &Message{
-		Pattern:   string(addr),
-		Arguments: args,
-	}, nil
+// encodedSize returns the size of the message's encoding, if all of its
+// arguments are Sizers.
+func (m Message) encodedSize() (int, bool) {
+	n := m.pattern().EncodedSize()
+	tags := 2 // The ',' and the terminating 0.
+	for _, a := range m.Arguments {
+		s, ok := a.(Sizer)
+		if !ok {
+			return 0, false
+		}
+		tags += utf8.RuneLen(a.TypeTag())
+		n += s.EncodedSize()
+	}
+	return n + paddedSize(tags), true
 }
 
-// Append encodes the message and appends it to the provided slice.
-func (m Message) Append(b []byte) []byte {
-	addr := String(m.Pattern)
-	b = addr.Append(b)
+func (m Message) append(b []byte, canonical bool) []byte {
+	if n, ok := m.encodedSize(); ok {
+		b = slices.Grow(b, n)
+	}
+	b = m.pattern().Append(b)
 
-	typeTag := make([]rune, 0, len(m.Arguments)+1)
-	typeTag = append(typeTag, ',')
+	// Write the type tag directly, rather than building a String.
+	b = append(b, ',')
 	for _, a := range m.Arguments {
-		typeTag = append(typeTag, a.TypeTag())
+		b = utf8.AppendRune(b, a.TypeTag())
+	}
+	b = append(b, 0)
+	for len(b)%4 > 0 {
+		b = append(b, 0)
 	}
-	tt := String(typeTag)
-	b = tt.Append(b)
 
 	for _, a := range m.Arguments {
+		if c, ok := a.(canonicalAppender); ok && canonical {
+			b = c.appendCanonical(b)
+			continue
+		}
 		b = a.Append(b)
 	}
 	return b
 }
 
+// pattern returns the pattern to encode, "/" if it's empty.
+func (m Message) pattern() String {
+	if m.Pattern == "" {
+		return "/"
+	}
+	return String(m.Pattern)
+}
+
 // TypeTag returns the message's type tag.
 func (m Message) TypeTag() string {
 	tags := make([]rune, len(m.Arguments))
@@ -99,14 +193,22 @@ func (m Message) CheckTypes(tt string) error {
 
 // newByTypeTag holds functions to construct new arguments from a given typetag.
 var newByTypeTag = map[rune]func() Argument{
-	Int32(0).TypeTag():   func() Argument { return new(Int32) },
-	Float32(0).TypeTag(): func() Argument { return new(Float32) },
-	String("").TypeTag(): func() Argument { return new(String) },
-	TimeTag{}.TypeTag():  func() Argument { return new(TimeTag) },
-	True{}.TypeTag():     func() Argument { return True{} },
-	False{}.TypeTag():    func() Argument { return False{} },
-	Null{}.TypeTag():     func() Argument { return Null{} },
-	Impulse{}.TypeTag():  func() Argument { return Impulse{} },
+	Int32(0).TypeTag():     func() Argument { return new(Int32) },
+	Int64(0).TypeTag():     func() Argument { return new(Int64) },
+	Float32(0).TypeTag():   func() Argument { return new(Float32) },
+	Float64(0).TypeTag():   func() Argument { return new(Float64) },
+	String("").TypeTag():   func() Argument { return new(String) },
+	Symbol("").TypeTag():   func() Argument { return new(Symbol) },
+	Char(0).TypeTag():      func() Argument { return new(Char) },
+	RGBA{}.TypeTag():       func() Argument { return new(RGBA) },
+	MIDI{}.TypeTag():       func() Argument { return new(MIDI) },
+	Blob{}.TypeTag():       func() Argument { return new(Blob) },
+	Compressed{}.TypeTag(): func() Argument { return new(Compressed) },
+	TimeTag{}.TypeTag():    func() Argument { return new(TimeTag) },
+	True{}.TypeTag():       func() Argument { return True{} },
+	False{}.TypeTag():      func() Argument { return False{} },
+	Null{}.TypeTag():       func() Argument { return Null{} },
+	Impulse{}.TypeTag():    func() Argument { return Impulse{} },
 }
 
 // Argument represents an OSC value.
@@ -121,18 +223,33 @@ type Argument interface {
 	Consume([]byte) ([]byte, error)
 }
 
+// Sizer is implemented by arguments that know the size of their encoding
+// without appending it, so messages can be encoded into a buffer allocated
+// once up front. All of the standard types implement it.
+type Sizer interface {
+	// EncodedSize returns the number of bytes Append would add.
+	EncodedSize() int
+}
+
+// paddedSize returns n rounded up to a multiple of 4.
+func paddedSize(n int) int {
+	return (n + 3) &^ 3
+}
+
 // Int32 is the OSC int32: a "32-bit big-endian two’s complement integer"
 type Int32 int32
 
 func (Int32) TypeTag() rune { return 'i' }
 
+func (Int32) EncodedSize() int { return 4 }
+
 func (i Int32) Append(b []byte) []byte {
 	return binary.BigEndian.AppendUint32(b, uint32(i))
 }
 
 func (i *Int32) Consume(b []byte) ([]byte, error) {
 	if l := len(b); l < 4 {
-		return nil, fmt.Errorf("expect int32, only %d bytes", l)
+		return nil, fmt.Errorf("expect int32, only %d bytes: %w", l, ErrShortBuffer)
 	}
 	u := binary.BigEndian.Uint32(b)
 	*i = Int32(u)
@@ -143,19 +260,60 @@ func (i Int32) String() string {
 	return fmt.Sprintf("Int32(%d)", i)
 }
 
+// Int64 is the non-standard but widely supported 'h' type: a 64-bit
+// big-endian two's complement integer.
+type Int64 int64
+
+func (Int64) TypeTag() rune { return 'h' }
+
+func (Int64) EncodedSize() int { return 8 }
+
+func (i Int64) Append(b []byte) []byte {
+	return binary.BigEndian.AppendUint64(b, uint64(i))
+}
+
+func (i *Int64) Consume(b []byte) ([]byte, error) {
+	if l := len(b); l < 8 {
+		return nil, fmt.Errorf("expect int64, only %d bytes: %w", l, ErrShortBuffer)
+	}
+	*i = Int64(binary.BigEndian.Uint64(b))
+	return b[8:], nil
+}
+
+func (i Int64) String() string {
+	return fmt.Sprintf("Int64(%d)", i)
+}
+
 // Float32 is a normal float32: "32-bit big-endian IEEE 754 floating point
 // number"
 type Float32 float32
 
 func (Float32) TypeTag() rune { return 'f' }
 
+func (Float32) EncodedSize() int { return 4 }
+
 func (f Float32) Append(b []byte) []byte {
 	return binary.BigEndian.AppendUint32(b, math.Float32bits(float32(f)))
 }
 
+// canonicalNaN32 is the quiet NaN used for all float32 NaNs in canonical
+// encodings.
+const canonicalNaN32 = 0x7fc00000
+
+func (f Float32) appendCanonical(b []byte) []byte {
+	switch {
+	case f != f:
+		return binary.BigEndian.AppendUint32(b, canonicalNaN32)
+	case f == 0:
+		// Includes negative zero.
+		f = 0
+	}
+	return f.Append(b)
+}
+
 func (f *Float32) Consume(b []byte) ([]byte, error) {
 	if l := len(b); l < 4 {
-		return nil, fmt.Errorf("expect float32, only %d bytes", l)
+		return nil, fmt.Errorf("expect float32, only %d bytes: %w", l, ErrShortBuffer)
 	}
 	u := binary.BigEndian.Uint32(b)
 	*f = Float32(math.Float32frombits(u))
@@ -166,12 +324,53 @@ func (f Float32) String() string {
 	return fmt.Sprintf("Float32(%f)", f)
 }
 
+// Float64 is the OSC 1.0 optional 'd' type: a 64-bit big-endian IEEE 754
+// floating point number.
+type Float64 float64
+
+func (Float64) TypeTag() rune { return 'd' }
+
+func (Float64) EncodedSize() int { return 8 }
+
+func (f Float64) Append(b []byte) []byte {
+	return binary.BigEndian.AppendUint64(b, math.Float64bits(float64(f)))
+}
+
+// canonicalNaN64 is the quiet NaN used for all float64 NaNs in canonical
+// encodings.
+const canonicalNaN64 = 0x7ff8000000000000
+
+func (f Float64) appendCanonical(b []byte) []byte {
+	switch {
+	case f != f:
+		return binary.BigEndian.AppendUint64(b, canonicalNaN64)
+	case f == 0:
+		// Includes negative zero.
+		f = 0
+	}
+	return f.Append(b)
+}
+
+func (f *Float64) Consume(b []byte) ([]byte, error) {
+	if l := len(b); l < 8 {
+		return nil, fmt.Errorf("expect float64, only %d bytes: %w", l, ErrShortBuffer)
+	}
+	*f = Float64(math.Float64frombits(binary.BigEndian.Uint64(b)))
+	return b[8:], nil
+}
+
+func (f Float64) String() string {
+	return fmt.Sprintf("Float64(%f)", f)
+}
+
 // String is an ASCII string, on the wire it's null-terminated and padded for
 // alignment.
 type String string
 
 func (String) TypeTag() rune { return 's' }
 
+func (s String) EncodedSize() int { return paddedSize(len(s) + 1) }
+
 func (s String) Append(b []byte) []byte {
 	// Avoid a temporary conversion.
 	for i := range s {
@@ -187,11 +386,22 @@ func (s String) Append(b []byte) []byte {
 }
 
 func (s *String) Consume(b []byte) ([]byte, error) {
+	str, b, err := consumeString(b)
+	if err != nil {
+		return nil, err
+	}
+	*s = String(str)
+	return b, nil
+}
+
+// consumeString reads a string from the front of b, returning its contents
+// without the termination and the remainder of b after the padding.
+func consumeString(b []byte) (str, rem []byte, err error) {
 	end := bytes.IndexByte(b, 0)
 	if end < 0 {
-		return nil, fmt.Errorf("no termination in string %q", b)
+		return nil, nil, fmt.Errorf("no termination in string %q: %w", b, ErrUnterminatedString)
 	}
-	*s = String(b[:end])
+	str = b[:end]
 	// Total number of bytes must be a multiple of 4, so we can just
 	// figure out how much padding there is from the length. Because
 	// the spec requires the padding, don't worry about whether the bytes
@@ -199,17 +409,157 @@ func (s *String) Consume(b []byte) ([]byte, error) {
 	// TODO: is this an ok assumption?
 	// TODO: maybe we should actually check the padding is correct?
 	end = min(end+4-end%4, len(b))
-	return b[end:], nil
+	return str, b[end:], nil
 }
 
 func (s String) String() string {
 	return fmt.Sprintf("String(%q)", string(s))
 }
 
+// Symbol is the alternate string type 'S', used by Max/MSP and liblo. It's
+// encoded exactly like a String, but keeps its own type tag.
+type Symbol string
+
+func (Symbol) TypeTag() rune { return 'S' }
+
+func (s Symbol) EncodedSize() int { return String(s).EncodedSize() }
+
+func (s Symbol) Append(b []byte) []byte { return String(s).Append(b) }
+
+func (s *Symbol) Consume(b []byte) ([]byte, error) {
+	str, b, err := consumeString(b)
+	if err != nil {
+		return nil, err
+	}
+	*s = Symbol(str)
+	return b, nil
+}
+
+func (s Symbol) String() string {
+	return fmt.Sprintf("Symbol(%q)", string(s))
+}
+
+// Char is the OSC 1.0 optional 'c' type: "an ascii character, sent as 32
+// bits".
+type Char rune
+
+func (Char) TypeTag() rune { return 'c' }
+
+func (Char) EncodedSize() int { return 4 }
+
+func (c Char) Append(b []byte) []byte {
+	return binary.BigEndian.AppendUint32(b, uint32(c))
+}
+
+func (c *Char) Consume(b []byte) ([]byte, error) {
+	if l := len(b); l < 4 {
+		return nil, fmt.Errorf("expect char, only %d bytes: %w", l, ErrShortBuffer)
+	}
+	*c = Char(binary.BigEndian.Uint32(b))
+	return b[4:], nil
+}
+
+func (c Char) String() string {
+	return fmt.Sprintf("Char(%q)", rune(c))
+}
+
+// RGBA is the OSC 1.0 optional 'r' type: a 32-bit color, one byte for each of
+// red, green, blue and alpha.
+type RGBA struct {
+	R, G, B, A byte
+}
+
+func (RGBA) TypeTag() rune { return 'r' }
+
+func (RGBA) EncodedSize() int { return 4 }
+
+func (c RGBA) Append(b []byte) []byte {
+	return append(b, c.R, c.G, c.B, c.A)
+}
+
+func (c *RGBA) Consume(b []byte) ([]byte, error) {
+	if l := len(b); l < 4 {
+		return nil, fmt.Errorf("expect rgba color, only %d bytes: %w", l, ErrShortBuffer)
+	}
+	*c = RGBA{R: b[0], G: b[1], B: b[2], A: b[3]}
+	return b[4:], nil
+}
+
+func (c RGBA) String() string {
+	return fmt.Sprintf("RGBA(%02x%02x%02x%02x)", c.R, c.G, c.B, c.A)
+}
+
+// MIDI is the OSC 1.0 optional 'm' type: a 4 byte MIDI message, "Bytes from
+// MSB to LSB are: port id, status byte, data1, data2".
+type MIDI struct {
+	Port, Status, Data1, Data2 byte
+}
+
+func (MIDI) TypeTag() rune { return 'm' }
+
+func (MIDI) EncodedSize() int { return 4 }
+
+func (m MIDI) Append(b []byte) []byte {
+	return append(b, m.Port, m.Status, m.Data1, m.Data2)
+}
+
+func (m *MIDI) Consume(b []byte) ([]byte, error) {
+	if l := len(b); l < 4 {
+		return nil, fmt.Errorf("expect midi message, only %d bytes: %w", l, ErrShortBuffer)
+	}
+	*m = MIDI{Port: b[0], Status: b[1], Data1: b[2], Data2: b[3]}
+	return b[4:], nil
+}
+
+func (m MIDI) String() string {
+	return fmt.Sprintf("MIDI(%d, %#02x, %d, %d)", m.Port, m.Status, m.Data1, m.Data2)
+}
+
+// Blob is arbitrary binary data. On the wire it's an int32 size followed by
+// that many bytes, padded with zeros to a multiple of 4 bytes.
+type Blob []byte
+
+func (Blob) TypeTag() rune { return 'b' }
+
+func (bl Blob) EncodedSize() int { return 4 + paddedSize(len(bl)) }
+
+func (bl Blob) Append(b []byte) []byte {
+	b = binary.BigEndian.AppendUint32(b, uint32(len(bl)))
+	b = append(b, bl...)
+	for len(b)%4 > 0 {
+		b = append(b, 0)
+	}
+	return b
+}
+
+func (bl *Blob) Consume(b []byte) ([]byte, error) {
+	if l := len(b); l < 4 {
+		return nil, fmt.Errorf("expect blob size, only %d bytes: %w", l, ErrShortBuffer)
+	}
+	n := int32(binary.BigEndian.Uint32(b))
+	b = b[4:]
+	if n < 0 || int(n) > len(b) {
+		return nil, fmt.Errorf("blob size %d, only %d bytes: %w", n, len(b), ErrShortBuffer)
+	}
+	// Copy the data out, the caller is likely to reuse the buffer.
+	*bl = bytes.Clone(b[:n])
+	end := min(int(n)+(4-int(n)%4)%4, len(b))
+	return b[end:], nil
+}
+
+func (bl Blob) String() string {
+	return fmt.Sprintf("Blob(%x)", []byte(bl))
+}
+
 // TimeTag is an OSC timetag. On the wire it's a "64-bit big-endian fixed-point
 // time tag" with the same encoding used by NTP. It's one of non-standard types
 // in the original spec, but it is mandatory in 1.1. We just wrap a time.Time so
 // it's easy to use, and assume everything is in UTC.
+//
+// The zero TimeTag is the special time tag meaning "immediately", raw value 1.
+// A time.Time is less precise than NTP, so other raw values may change a
+// little on a round trip through a TimeTag; programs that need parsed time
+// tags re-encoded exactly can register RawTimeTag with a Codec instead.
 type TimeTag struct {
 	time.Time
 }
@@ -219,36 +569,121 @@ func (TimeTag) TypeTag() rune { return 't' }
 // epoch is the starting point for TimeTags.
 var epoch = time.Date(1900, time.January, 1, 0, 0, 0, 0, time.UTC)
 
+func (TimeTag) EncodedSize() int { return 8 }
+
 func (t TimeTag) Append(b []byte) []byte {
-	seconds := t.Sub(epoch).Seconds()
-	if seconds <= 0 {
-		// A go time could be well before epoch, cut off anything there.
-		return append(b, 0, 0, 0, 0, 0, 0, 0, 0)
-	}
-	// The highest 4 bytes are the integer number of seconds and
-	// the lowest four bytes are however much of the fractional part
-	// fits in.
-	const stepsPerSecond = float64(int64(1) << 32)
-	base, frac := math.Modf(seconds)
-	out := (uint64(base) << 32) + uint64(frac*stepsPerSecond)
-	return binary.BigEndian.AppendUint64(b, out)
+	return binary.BigEndian.AppendUint64(b, t.Raw())
 }
 
 func (t *TimeTag) Consume(b []byte) ([]byte, error) {
 	if l := len(b); l < 8 {
-		return nil, fmt.Errorf("expected timetag (8 bytes), only %d bytes", l)
+		return nil, fmt.Errorf("expected timetag (8 bytes), only %d bytes: %w", l, ErrShortBuffer)
 	}
-	raw := binary.BigEndian.Uint64(b)
-	seconds := float64(raw >> 32)
-	seconds += float64(raw&0xffffffff) / float64(1<<32)
-	*t = TimeTag{epoch.Add(time.Duration(seconds * float64(time.Second)))}
+	*t = FromRaw(binary.BigEndian.Uint64(b))
 	return b[8:], nil
 }
 
+// Raw returns the time tag's NTP representation: the highest 4 bytes are the
+// seconds since 1900 and the lowest 4 bytes are the fractional part, rounded to
+// the nearest 1/2^32 of a second. Times before 1900 are cut off at 0 and, as in
+// NTP, the seconds wrap around in 2036. The zero TimeTag is 1, immediately.
+func (t TimeTag) Raw() uint64 {
+	if t.IsZero() {
+		return 1
+	}
+	seconds := t.Unix() - epoch.Unix()
+	if seconds < 0 {
+		return 0
+	}
+	// A carry out of the fraction correctly ends up in the seconds.
+	frac := (uint64(t.Nanosecond())<<32 + 1e9/2) / 1e9
+	return uint64(seconds)<<32 + frac
+}
+
+// FromRaw returns the TimeTag for an NTP time, see TimeTag.Raw. A time.Time is
+// less precise than NTP, so the fractional part is rounded to the nearest
+// nanosecond. This means t.Raw() round trips through FromRaw exactly, but a raw
+// value may be a little different after a round trip through a TimeTag. The
+// value 1, immediately, is the zero TimeTag.
+func FromRaw(raw uint64) TimeTag {
+	if raw == 1 {
+		return TimeTag{}
+	}
+	seconds := int64(raw >> 32)
+	nanos := ((raw&0xffffffff)*1e9 + 1<<31) >> 32
+	return TimeTag{time.Unix(epoch.Unix()+seconds, int64(nanos)).UTC()}
+}
+
+// Clock returns the current time. time.Now is a Clock, programs synchronised to
+// something else, such as an audio device, can use their own.
+type Clock func() time.Time
+
+// Now returns the TimeTag for the current time from clock, or from time.Now if
+// it's nil.
+func Now(clock Clock) TimeTag {
+	if clock == nil {
+		clock = time.Now
+	}
+	return TimeTag{clock()}
+}
+
+// AddRaw adds d to the NTP time raw, see TimeTag.Raw, using only integer
+// arithmetic. It's for programs emitting many time tags at fixed offsets from
+// one base, such as a scheduler: take the Raw of the base once, then AddRaw
+// each offset.
+func AddRaw(raw uint64, d time.Duration) uint64 {
+	neg := d < 0
+	if neg {
+		d = -d
+	}
+	secs, nanos := uint64(d/time.Second), uint64(d%time.Second)
+	delta := secs<<32 + (nanos<<32+1e9/2)/1e9
+	if neg {
+		return raw - delta
+	}
+	return raw + delta
+}
+
 func (t TimeTag) String() string {
+	if t.IsZero() {
+		return "TimeTag(immediately)"
+	}
 	return fmt.Sprintf("TimeTag(%v)", t.Time)
 }
 
+// RawTimeTag is a time tag kept as its NTP representation, see TimeTag.Raw, so
+// it's encoded exactly as it was parsed. Parsing gives TimeTags by default, a
+// Codec can be set up to give RawTimeTags instead:
+//
+//	c := osc.NewCodec()
+//	c.Register('t', func() osc.Argument { return new(osc.RawTimeTag) })
+type RawTimeTag uint64
+
+func (RawTimeTag) TypeTag() rune { return 't' }
+
+func (RawTimeTag) EncodedSize() int { return 8 }
+
+func (r RawTimeTag) Append(b []byte) []byte {
+	return binary.BigEndian.AppendUint64(b, uint64(r))
+}
+
+func (r *RawTimeTag) Consume(b []byte) ([]byte, error) {
+	if l := len(b); l < 8 {
+		return nil, fmt.Errorf("expected timetag (8 bytes), only %d bytes: %w", l, ErrShortBuffer)
+	}
+	*r = RawTimeTag(binary.BigEndian.Uint64(b))
+	return b[8:], nil
+}
+
+// TimeTag returns the time tag as a TimeTag, see FromRaw.
+func (r RawTimeTag) TimeTag() TimeTag {
+	return FromRaw(uint64(r))
+}
+
+func (r RawTimeTag) String() string {
+	return fmt.Sprintf("RawTimeTag(%016x)", uint64(r))
+}
+
 /*
    Additional mandatory types from the OSC 1.1 NIME paper
    (https://ccrma.stanford.edu/groups/osc/files/2009-NIME-OSC-1.1.pdf)
@@ -259,6 +694,7 @@ type True struct{}
 
 func (True) TypeTag() rune                    { return 'T' }
 func (True) Append(b []byte) []byte           { return b }
+func (True) EncodedSize() int                 { return 0 }
 func (True) Consume(b []byte) ([]byte, error) { return b, nil }
 func (True) String() string                   { return "True" }
 
@@ -267,6 +703,7 @@ type False struct{}
 
 func (False) TypeTag() rune                    { return 'F' }
 func (False) Append(b []byte) []byte           { return b }
+func (False) EncodedSize() int                 { return 0 }
 func (False) Consume(b []byte) ([]byte, error) { return b, nil }
 func (False) String() string                   { return "False" }
 
@@ -275,6 +712,7 @@ type Null struct{}
 
 func (Null) TypeTag() rune                    { return 'N' }
 func (Null) Append(b []byte) []byte           { return b }
+func (Null) EncodedSize() int                 { return 0 }
 func (Null) Consume(b []byte) ([]byte, error) { return b, nil }
 func (Null) String() string                   { return "Null" }
 
@@ -283,5 +721,6 @@ type Impulse struct{}
 
 func (Impulse) TypeTag() rune                    { return 'I' }
 func (Impulse) Append(b []byte) []byte           { return b }
+func (Impulse) EncodedSize() int                 { return 0 }
 func (Impulse) Consume(b []byte) ([]byte, error) { return b, nil }
 func (Impulse) String() string                   { return "Impulse" }
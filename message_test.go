@@ -3,11 +3,13 @@ package osc
 import (
 	"bytes"
 	"encoding/binary"
+	"errors"
 	"math"
 	"math/rand"
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestMessageRoundtrip(t *testing.T) {
@@ -29,15 +31,44 @@ func TestMessageRoundtrip(t *testing.T) {
 			i := Int32(rand.Int31())
 			return &i
 		},
+		func() Argument {
+			i := Int64(rand.Int63())
+			return &i
+		},
 		func() Argument {
 			u := rand.Uint32()
 			f := Float32(math.Float32frombits(u))
 			return &f
 		},
+		func() Argument {
+			f := Float64(rand.NormFloat64() * math.MaxFloat32)
+			return &f
+		},
 		func() Argument {
 			s := String(str())
 			return &s
 		},
+		func() Argument {
+			s := Symbol(str())
+			return &s
+		},
+		func() Argument {
+			c := Char(rand.Intn(128))
+			return &c
+		},
+		func() Argument {
+			u := rand.Uint32()
+			return &RGBA{byte(u >> 24), byte(u >> 16), byte(u >> 8), byte(u)}
+		},
+		func() Argument {
+			u := rand.Uint32()
+			return &MIDI{byte(u >> 24), byte(u >> 16), byte(u >> 8), byte(u)}
+		},
+		func() Argument {
+			b := make(Blob, rand.Intn(maxString))
+			rand.Read(b)
+			return &b
+		},
 		func() Argument {
 			return True{}
 		},
@@ -80,12 +111,22 @@ func TestMessageRoundtrip(t *testing.T) {
 
 	for _, msg := range msgs {
 		enc := msg.Append(nil)
+		if n, ok := msg.encodedSize(); !ok || n != len(enc) {
+			t.Errorf("encodedSize() = %d, %t, want: %d, true\n(%v)", n, ok, len(enc), msg)
+		}
+		if n := msg.EncodedSize(); n != len(enc) {
+			t.Errorf("EncodedSize() = %d, want: %d\n(%v)", n, len(enc), msg)
+		}
 		got, err := ParseMessage(enc)
 		if err != nil {
 			t.Errorf("ParseMessage: %v\n(%v)", err, msg)
 			continue
 		}
 		gotEnc := got.Append(nil)
+		if msg.Pattern == "" {
+			// Encoded as the root, so it's a valid packet.
+			msg.Pattern = "/"
+		}
 		if msg.Arguments == nil {
 			msg.Arguments = []Argument{}
 		}
@@ -180,6 +221,93 @@ func TestFloat32(t *testing.T) {
 	}
 }
 
+func TestTimeTagRaw(t *testing.T) {
+	unix := uint64(2208988800) << 32
+	if got := (TimeTag{time.Unix(0, 0)}).Raw(); got != unix {
+		t.Errorf("Raw() of unix epoch = %x, want %x", got, unix)
+	}
+	if got := FromRaw(unix + 1<<31); !got.Equal(time.Unix(0, 5e8)) {
+		t.Errorf("FromRaw(%x) = %v, want: %v", unix+1<<31, got, time.Unix(0, 5e8))
+	}
+	if got := (TimeTag{time.Date(1800, 1, 1, 0, 0, 0, 0, time.UTC)}).Raw(); got != 0 {
+		t.Errorf("Raw() before 1900 = %x, want 0", got)
+	}
+
+	for i := 0; i < 10000; i++ {
+		raw := rand.Uint64()
+		tt := FromRaw(raw)
+		// Nanoseconds are coarser than the fixed point fraction, so
+		// allow for rounding.
+		if diff := int64(tt.Raw() - raw); diff < -4 || diff > 4 {
+			t.Errorf("FromRaw(%x).Raw() = %x", raw, tt.Raw())
+		}
+		if got := FromRaw(tt.Raw()); !got.Equal(tt.Time) {
+			t.Errorf("FromRaw(%v.Raw()) = %v", tt, got)
+		}
+	}
+}
+
+func TestTimeTagImmediately(t *testing.T) {
+	if got := FromRaw(1); got != (TimeTag{}) || got.Raw() != 1 {
+		t.Errorf("FromRaw(1) = %v with Raw() %x, want: TimeTag{} with Raw() 1", got, got.Raw())
+	}
+	var parsed TimeTag
+	if _, err := parsed.Consume(TimeTag{}.Append(nil)); err != nil || parsed != (TimeTag{}) {
+		t.Errorf("Consume(TimeTag{}.Append()) = %v, %v, want: %v", parsed, err, TimeTag{})
+	}
+	if got := (TimeTag{}).String(); got != "TimeTag(immediately)" {
+		t.Errorf("TimeTag{}.String() = %q, want: %q", got, "TimeTag(immediately)")
+	}
+}
+
+func TestRawTimeTag(t *testing.T) {
+	c := NewCodec()
+	c.Register('t', func() Argument { return new(RawTimeTag) })
+	for i := 0; i < 1000; i++ {
+		raw := RawTimeTag(rand.Uint64())
+		b := (&Message{Pattern: "/at", Arguments: []Argument{&raw}}).Append(nil)
+		msg, err := c.ParseMessage(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := *msg.Arguments[0].(*RawTimeTag); got != raw {
+			t.Errorf("parsed %v, want: %v", got, raw)
+		}
+		if got := msg.Append(nil); !bytes.Equal(got, b) {
+			t.Errorf("re-encoded %x, want: %x", got, b)
+		}
+		if got := raw.TimeTag(); got != FromRaw(uint64(raw)) {
+			t.Errorf("%v.TimeTag() = %v, want: %v", raw, got, FromRaw(uint64(raw)))
+		}
+	}
+}
+
+func TestAddRaw(t *testing.T) {
+	base := time.Date(2024, 5, 1, 12, 0, 0, 123456789, time.UTC)
+	raw := TimeTag{base}.Raw()
+	for _, d := range []time.Duration{0, time.Nanosecond, 1500 * time.Millisecond, time.Hour + 7, -2*time.Second - 3} {
+		want := TimeTag{base.Add(d)}.Raw()
+		// Both round to the nearest fraction, so may be one apart.
+		if got := AddRaw(raw, d); int64(got-want) < -1 || int64(got-want) > 1 {
+			t.Errorf("AddRaw(%x, %v) = %x, want: %x", raw, d, got, want)
+		}
+	}
+
+	clock := func() time.Time { return base }
+	if got := Now(clock); !got.Equal(base) {
+		t.Errorf("Now(clock) = %v, want: %v", got, base)
+	}
+	p, err := Prepare("/at", "t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if allocs := testing.AllocsPerRun(100, func() {
+		p.SetRawTimeTag(0, AddRaw(Now(clock).Raw(), 10*time.Millisecond))
+	}); allocs != 0 {
+		t.Errorf("setting a time tag allocated %.1f times, want: 0", allocs)
+	}
+}
+
 func TestStringConsume(t *testing.T) {
 	nt := func(s string) []byte {
 		b := append([]byte(s), 0)
@@ -257,6 +385,14 @@ func TestArgRoundTrip(t *testing.T) {
 			})
 		}
 	})
+	t.Run("Int64", func(t *testing.T) {
+		for i := 0; i < 100; i++ {
+			j := Int64(rand.Int63() - rand.Int63())
+			testArgRoundTrip(t, &j, func() *Int64 {
+				return new(Int64)
+			})
+		}
+	})
 	t.Run("Float32", func(t *testing.T) {
 		for i := 0; i < 100; i++ {
 			f := Float32(rand.Float32())
@@ -265,6 +401,14 @@ func TestArgRoundTrip(t *testing.T) {
 			})
 		}
 	})
+	t.Run("Float64", func(t *testing.T) {
+		for i := 0; i < 100; i++ {
+			f := Float64(rand.NormFloat64())
+			testArgRoundTrip(t, &f, func() *Float64 {
+				return new(Float64)
+			})
+		}
+	})
 	t.Run("String", func(t *testing.T) {
 		const chars = "1234567890abcdefghijklmnop"
 		inputs := make([]String, 100)
@@ -283,6 +427,66 @@ func TestArgRoundTrip(t *testing.T) {
 			})
 		}
 	})
+	t.Run("Symbol", func(t *testing.T) {
+		for _, s := range []Symbol{"", "a", "abc", "abcd", "bang"} {
+			testArgRoundTrip(t, &s, func() *Symbol {
+				return new(Symbol)
+			})
+		}
+	})
+	t.Run("Char", func(t *testing.T) {
+		for c := Char(0); c < 128; c++ {
+			testArgRoundTrip(t, &c, func() *Char {
+				return new(Char)
+			})
+		}
+	})
+	t.Run("RGBA", func(t *testing.T) {
+		if got := (RGBA{R: 1, G: 2, B: 3, A: 4}).Append(nil); !bytes.Equal(got, []byte{1, 2, 3, 4}) {
+			t.Errorf("RGBA{1, 2, 3, 4}.Append = %x, want: 01020304", got)
+		}
+		for i := 0; i < 100; i++ {
+			u := rand.Uint32()
+			c := RGBA{byte(u >> 24), byte(u >> 16), byte(u >> 8), byte(u)}
+			testArgRoundTrip(t, &c, func() *RGBA {
+				return new(RGBA)
+			})
+		}
+	})
+	t.Run("MIDI", func(t *testing.T) {
+		// Note on, middle C, on port 1.
+		if got := (MIDI{Port: 1, Status: 0x90, Data1: 60, Data2: 100}).Append(nil); !bytes.Equal(got, []byte{1, 0x90, 60, 100}) {
+			t.Errorf("MIDI.Append = %x, want: 01903c64", got)
+		}
+		for i := 0; i < 100; i++ {
+			u := rand.Uint32()
+			m := MIDI{byte(u >> 24), byte(u >> 16), byte(u >> 8), byte(u)}
+			testArgRoundTrip(t, &m, func() *MIDI {
+				return new(MIDI)
+			})
+		}
+	})
+	t.Run("Blob", func(t *testing.T) {
+		for i := 0; i < 100; i++ {
+			b := make(Blob, rand.Intn(25))
+			rand.Read(b)
+			testArgRoundTrip(t, &b, func() *Blob {
+				return new(Blob)
+			})
+		}
+	})
+	t.Run("Compressed", func(t *testing.T) {
+		for i := 0; i < 100; i++ {
+			c := &Compressed{
+				Method: Compression(rand.Intn(2)),
+				Data:   make(Blob, rand.Intn(1000)),
+			}
+			rand.Read(c.Data[:len(c.Data)/2]) // leave something to compress
+			testArgRoundTrip(t, c, func() *Compressed {
+				return new(Compressed)
+			})
+		}
+	})
 	t.Run("TimeTag", func(t *testing.T) {
 		for i := 0; i < 100; i++ {
 			b := make([]byte, 8)
@@ -335,3 +539,191 @@ func testArgRoundTrip[T Argument](t *testing.T, a T, mk func() T) {
 		t.Errorf("Round trip (%c) filed: wrong leftovers after Consume:\n got: %x\nwant: %x", a.TypeTag(), gotTail, tail)
 	}
 }
+
+func TestCapabilities(t *testing.T) {
+	caps := Capabilities{CapGzip: true, "something/else": true, "not/supported": false}
+	got, err := ParseCapabilities(caps.Message())
+	if err != nil {
+		t.Fatalf("ParseCapabilities: %v", err)
+	}
+	want := Capabilities{CapGzip: true, "something/else": true}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseCapabilities(%v) = %v, want: %v", caps.Message(), got, want)
+	}
+	// The encoding doesn't depend on map order.
+	sorted := Message{Pattern: CapabilitiesAddress, Arguments: []Argument{AsString(CapGzip), AsString("something/else")}}
+	if b := caps.Message().Append(nil); !bytes.Equal(b, sorted.Append(nil)) {
+		t.Errorf("%v.Message() encoded as %q, want: %q", caps, b, sorted.Append(nil))
+	}
+	if _, err := ParseCapabilities(&Message{Pattern: "/other"}); err == nil {
+		t.Error("ParseCapabilities: expected error for wrong address")
+	}
+}
+
+func TestMandatoryTypeTags(t *testing.T) {
+	for _, tag := range MandatoryTypeTags {
+		if _, ok := newByTypeTag[tag]; !ok {
+			t.Errorf("mandatory type %q not supported", tag)
+		}
+	}
+}
+
+func TestParseMessageInto(t *testing.T) {
+	f := Float32(0.5)
+	msgs := []*Message{{
+		Pattern:   "/a",
+		Arguments: []Argument{AsInt32(1), &f, AsString("hi")},
+	}, {
+		Pattern:   "/a",
+		Arguments: []Argument{AsString("types changed"), AsInt32(2)},
+	}, {
+		Pattern:   "/b",
+		Arguments: []Argument{},
+	}, {
+		Pattern:   "/b",
+		Arguments: []Argument{AsInt32(3), True{}, AsInt32(4)},
+	}}
+	m := AcquireMessage()
+	defer ReleaseMessage(m)
+	for _, msg := range msgs {
+		m.Reset()
+		if err := ParseMessageInto(m, msg.Append(nil)); err != nil {
+			t.Fatalf("ParseMessageInto(%v): %v", msg, err)
+		}
+		if !reflect.DeepEqual(m, msg) {
+			t.Errorf("ParseMessageInto = %v, want: %v", m, msg)
+		}
+	}
+
+	// Parsing the same shape of message again shouldn't allocate.
+	enc := (&Message{
+		Pattern:   "/fader",
+		Arguments: []Argument{AsInt32(1), &f},
+	}).Append(nil)
+	allocs := testing.AllocsPerRun(100, func() {
+		if err := ParseMessageInto(m, enc); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs > 0 {
+		t.Errorf("ParseMessageInto allocated %.1f times per message", allocs)
+	}
+}
+
+func TestAppendCanonical(t *testing.T) {
+	f := func(bits uint32) Argument {
+		f := Float32(math.Float32frombits(bits))
+		return &f
+	}
+	d := func(bits uint64) Argument {
+		d := Float64(math.Float64frombits(bits))
+		return &d
+	}
+	msg := func(args ...Argument) *Message {
+		return &Message{Pattern: "/canon", Arguments: append(args, AsString("other"), AsInt32(-1))}
+	}
+	for _, c := range []struct {
+		a, b *Message
+	}{
+		{msg(f(0)), msg(f(0x80000000))},                          // negative zero
+		{msg(f(0x7fc00000)), msg(f(0x7f800001))},                 // signalling NaN
+		{msg(f(0x7fc00000)), msg(f(0xffc00123))},                 // negative NaN with payload
+		{msg(d(0)), msg(d(1 << 63))},                             // negative zero double
+		{msg(d(0x7ff8000000000000)), msg(d(0xfff8000000000123))}, // negative NaN double
+	} {
+		encA, encB := c.a.Append(nil), c.b.Append(nil)
+		if bytes.Equal(encA, encB) {
+			t.Errorf("Append(%v) == Append(%v), expected different encodings", c.a, c.b)
+		}
+		canonA, canonB := c.a.AppendCanonical(nil), c.b.AppendCanonical(nil)
+		if !bytes.Equal(canonA, canonB) {
+			t.Errorf("AppendCanonical differs:\n%v: %x\n%v: %x", c.a, canonA, c.b, canonB)
+		}
+	}
+	// Without anything to normalise the encodings should be the same.
+	m := msg(f(math.Float32bits(1.5)), &Blob{1, 2, 3})
+	if a, b := m.Append(nil), m.AppendCanonical(nil); !bytes.Equal(a, b) {
+		t.Errorf("Append = %x, AppendCanonical = %x", a, b)
+	}
+}
+
+func TestAppendAllocs(t *testing.T) {
+	if raceEnabled {
+		t.Skip("allocation counts are unreliable with the race detector")
+	}
+	b := make(Blob, 1000)
+	msg := Message{Pattern: "/big", Arguments: []Argument{AsInt32(1), &b, AsString("a string"), &TimeTag{}, Null{}}}
+	for range 50 {
+		msg.Arguments = append(msg.Arguments, &b)
+	}
+	if allocs := testing.AllocsPerRun(100, func() { msg.Append(nil) }); allocs != 1 {
+		t.Errorf("Append(nil) allocated %.1f times, want: 1", allocs)
+	}
+}
+
+func TestBool(t *testing.T) {
+	for _, b := range []bool{true, false} {
+		msg := Message{Pattern: "/toggle", Arguments: []Argument{AsBool(b)}}
+		got, err := ParseMessage(msg.Append(nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v, ok := BoolValue(got.Arguments[0]); !ok || v != b {
+			t.Errorf("BoolValue(round trip of AsBool(%t)) = %t, %t, want: %t, true", b, v, ok, b)
+		}
+	}
+	if _, ok := BoolValue(AsInt32(1)); ok {
+		t.Error("BoolValue(Int32) ok = true, want: false")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if !(Message{}).IsZero() {
+		t.Error("Message{}.IsZero() = false, want: true")
+	}
+	if (Message{Pattern: "/"}).IsZero() {
+		t.Error(`Message{Pattern: "/"}.IsZero() = true, want: false`)
+	}
+	for _, c := range []struct {
+		msg  Message
+		want error
+	}{
+		{Message{Pattern: "/a", Arguments: []Argument{AsString("ok")}}, nil},
+		{Message{}, ErrBadAddress},
+		{Message{Pattern: "a"}, ErrBadAddress},
+		{Message{Pattern: "/a\x00b"}, ErrBadAddress},
+	} {
+		if err := c.msg.Validate(); !errors.Is(err, c.want) {
+			t.Errorf("%v.Validate() = %v, want: %v", c.msg, err, c.want)
+		}
+	}
+	sym := Symbol("a\x00b")
+	for name, args := range map[string][]Argument{
+		"nil argument":       {nil},
+		"string with a zero": {AsString("a\x00b")},
+		"symbol with a zero": {&sym},
+	} {
+		if err := (Message{Pattern: "/a", Arguments: args}).Validate(); err == nil {
+			t.Errorf("Validate() with %s = nil, want error", name)
+		}
+	}
+}
+
+func TestAppendZero(t *testing.T) {
+	want := []byte("/\x00\x00\x00,\x00\x00\x00")
+	if got := (Message{}).Append(nil); !bytes.Equal(got, want) {
+		t.Errorf("Message{}.Append() = %q, want: %q", got, want)
+	}
+	msg, err := ParseMessage(want)
+	if err != nil || msg.Validate() != nil {
+		t.Errorf("ParseMessage(Message{}.Append()) = %v, %v, want a valid message", msg, err)
+	}
+}
+
+func TestEncodedSize(t *testing.T) {
+	data := Blob(strings.Repeat("compressible ", 20))
+	msg := Message{Pattern: "/big", Arguments: []Argument{AsInt32(1), CompressBlob(data, 0)}}
+	if got, want := msg.EncodedSize(), len(msg.Append(nil)); got != want {
+		t.Errorf("EncodedSize() with Compressed = %d, want: %d", got, want)
+	}
+}
@@ -0,0 +1,5 @@
+//go:build !race
+
+package osc
+
+const raceEnabled = false
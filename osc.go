@@ -1,55 +1,48 @@
 // package osc sends and receives Open Sound Control messages, per the
 // OSC 1.0 spec (https://ccrma.stanford.edu/groups/osc/spec-1_0.html)
+//
+// When built with TinyGo (or the tinygo build tag) the package only provides
+// encoding and decoding, leaving out everything that needs the net package, so
+// it can be used in microcontroller firmware.
 package osc
 
-import (
-	"net"
-	"sync"
-
-	"golang.org/x/exp/constraints"
-)
-
-// Send builds and sends a message using the provided arguments, to the given
-// pattern at the provided address.
-// TODO: not a great api?
-func Send(conn net.PacketConn, addr, pattern string, args ...Argument) error {
-	nAddr, err := net.ResolveUDPAddr("udp", addr)
-	if err != nil {
-		return err
-	}
-	msg := Message{
-		Pattern:   pattern,
-		Arguments: args,
-	}
-	b := getBuf()
-	b = msg.Append(b)
-	defer putBuf(b)
-	_, err = conn.WriteTo(b, nAddr)
-	return err
+func AsString(s string) *String {
+	os := String(s)
+	return &os
 }
 
-var bufPool = sync.Pool{
-	New: func() any {
-		b := make([]byte, 1024)
-		return &b
-	},
+// integer is any integer type.
+type integer interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr
 }
 
-func getBuf() []byte {
-	b := bufPool.Get().(*[]byte)
-	return (*b)[:0]
+func AsInt32[T integer](i T) *Int32 {
+	ii := Int32(i)
+	return &ii
 }
 
-func putBuf(b []byte) {
-	bufPool.Put(&b)
+func AsInt64[T integer](i T) *Int64 {
+	ii := Int64(i)
+	return &ii
 }
 
-func AsString(s string) *String {
-	os := String(s)
-	return &os
+// AsBool returns True or False, the two argument types encoding a bool.
+func AsBool(b bool) Argument {
+	if b {
+		return True{}
+	}
+	return False{}
 }
 
-func AsInt32[T constraints.Integer](i T) *Int32 {
-	ii := Int32(i)
-	return &ii
+// BoolValue returns the bool a True or False argument encodes, and false for
+// ok if the argument is neither.
+func BoolValue(a Argument) (v, ok bool) {
+	switch a.(type) {
+	case True:
+		return true, true
+	case False:
+		return false, true
+	}
+	return false, false
 }
@@ -0,0 +1,95 @@
+// package osctest provides utilities for testing programs that send and receive
+// OSC.
+package osctest
+
+import (
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+)
+
+// Conditions describes how a LossyConn should mistreat packets.
+type Conditions struct {
+	// Latency is added to every packet.
+	Latency time.Duration
+	// Jitter is the maximum additional random delay added to each packet.
+	// Packets with different delays may arrive out of order.
+	Jitter time.Duration
+	// Loss is the probability that a packet is dropped.
+	Loss float64
+	// Duplicate is the probability that a packet is sent twice.
+	Duplicate float64
+	// Reorder is the probability that a packet is held back by an extra
+	// Latency + Jitter, so that it is likely to arrive after the packets
+	// sent after it.
+	Reorder float64
+	// Seed seeds the random decisions, so a test can be repeated exactly
+	// (aside from the timing of the underlying network).
+	Seed int64
+}
+
+// LossyConn is a net.PacketConn that emulates an unreliable network: packets
+// written to it are delayed, reordered, duplicated or dropped according to its
+// Conditions before being written to the wrapped connection. Reads are passed
+// straight through.
+type LossyConn struct {
+	net.PacketConn
+	c Conditions
+
+	mu  sync.Mutex
+	rng *rand.Rand
+}
+
+// NewLossyConn wraps conn, applying c to all packets written to it.
+func NewLossyConn(conn net.PacketConn, c Conditions) *LossyConn {
+	return &LossyConn{
+		PacketConn: conn,
+		c:          c,
+		rng:        rand.New(rand.NewSource(c.Seed)),
+	}
+}
+
+// WriteTo writes a packet according to the conn's Conditions. A packet that is
+// dropped or delayed is still reported as written in full. Errors writing
+// delayed packets are discarded, as they would be by a real network.
+func (l *LossyConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	l.mu.Lock()
+	if l.rng.Float64() < l.c.Loss {
+		l.mu.Unlock()
+		return len(b), nil
+	}
+	copies := 1
+	if l.rng.Float64() < l.c.Duplicate {
+		copies = 2
+	}
+	delays := make([]time.Duration, copies)
+	for i := range delays {
+		delays[i] = l.delay()
+	}
+	l.mu.Unlock()
+
+	if copies == 1 && delays[0] == 0 {
+		return l.PacketConn.WriteTo(b, addr)
+	}
+	// The caller may reuse b once we return.
+	b = append([]byte(nil), b...)
+	for _, d := range delays {
+		time.AfterFunc(d, func() {
+			l.PacketConn.WriteTo(b, addr)
+		})
+	}
+	return len(b), nil
+}
+
+// delay picks how long to hold a packet. l.mu must be held.
+func (l *LossyConn) delay() time.Duration {
+	d := l.c.Latency
+	if l.c.Jitter > 0 {
+		d += time.Duration(l.rng.Int63n(int64(l.c.Jitter)))
+	}
+	if l.rng.Float64() < l.c.Reorder {
+		d += l.c.Latency + l.c.Jitter
+	}
+	return d
+}
@@ -0,0 +1,57 @@
+package osctest
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func listen(t *testing.T) net.PacketConn {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// count reads packets from conn until none arrive for a while.
+func count(t *testing.T, conn net.PacketConn) int {
+	t.Helper()
+	buf := make([]byte, 100)
+	n := 0
+	for {
+		conn.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+		if _, _, err := conn.ReadFrom(buf); err != nil {
+			return n
+		}
+		n++
+	}
+}
+
+func TestLossyConn(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		c    Conditions
+		want int
+	}{
+		{"perfect", Conditions{}, 10},
+		{"lossy", Conditions{Loss: 1}, 0},
+		{"duplicate", Conditions{Duplicate: 1}, 20},
+		{"slow", Conditions{Latency: 10 * time.Millisecond, Jitter: 10 * time.Millisecond, Reorder: 0.5}, 10},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			recv := listen(t)
+			send := NewLossyConn(listen(t), c.c)
+			for range 10 {
+				if _, err := send.WriteTo([]byte("hello"), recv.LocalAddr()); err != nil {
+					t.Fatalf("WriteTo: %v", err)
+				}
+			}
+			if got := count(t, recv); got != c.want {
+				t.Errorf("received %d packets, want %d", got, c.want)
+			}
+		})
+	}
+}
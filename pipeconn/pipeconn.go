@@ -0,0 +1,352 @@
+// Package pipeconn provides a net.PacketConn over a pair of byte streams, such
+// as a subprocess's stdin and stdout, so OSC can be piped between a program and
+// its children. Because it's a net.PacketConn it can be used with osc.Send and
+// server.Listener just like a UDP socket.
+//
+// Packets are framed with SLIP (RFC 1055), double-ended as in OSC 1.1, which
+// most OSC libraries support for streams. The address passed to WriteTo is
+// ignored, there's only one peer. The same framing works over any stream, so
+// New(conn, conn) gives OSC over TCP or a serial port.
+//
+// Each packet is written to the stream as it's sent, unless write combining is
+// turned on with SetWriteCombining, trading latency for fewer, larger writes.
+package pipeconn
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// Addr is the address of a pipe: a name for the other end.
+type Addr string
+
+func (Addr) Network() string  { return "pipe" }
+func (a Addr) String() string { return string(a) }
+
+// Conn is a net.PacketConn over a pair of streams.
+type Conn struct {
+	w     io.Writer
+	addr  Addr
+	close func() error
+
+	// recv holds received packets. Unlike a UDP socket nothing is
+	// dropped, the other end blocks until packets are read.
+	recv chan []byte
+	// eof is closed when reading fails, with the error in readErr.
+	eof     chan struct{}
+	readErr error
+	closed  chan struct{}
+	once    sync.Once
+
+	wmu  sync.Mutex
+	wbuf []byte
+	// combine is the size wbuf can reach before it's written, if write
+	// combining, and flushAfter the longest a packet waits in it.
+	combine    int
+	flushAfter time.Duration
+	flushTimer *time.Timer
+	// werr is an error from a write by flushTimer, returned by the next
+	// WriteTo or Flush.
+	werr error
+
+	mu           sync.Mutex
+	readDeadline time.Time
+	// deadlineChanged is closed when the read deadline changes, to wake up
+	// any ReadFrom in progress.
+	deadlineChanged chan struct{}
+}
+
+var _ net.PacketConn = (*Conn)(nil)
+
+// recvQueue is the number of received packets buffered by a Conn.
+const recvQueue = 100
+
+// maxPacket is the size of the largest packet a Conn receives, the most that
+// fits in a UDP datagram. Longer frames are dropped.
+const maxPacket = 1 << 16
+
+// New returns a Conn reading packets from r and writing them to w. Closing it
+// closes r and w, if they're io.Closers.
+func New(r io.Reader, w io.Writer) *Conn {
+	return newConn(Addr("pipe"), r, w, func() error {
+		var errs []error
+		for _, s := range []any{w, r} {
+			if c, ok := s.(io.Closer); ok {
+				errs = append(errs, c.Close())
+			}
+		}
+		return errors.Join(errs...)
+	})
+}
+
+// Stdio returns a Conn on the process's stdin and stdout, for a program run as
+// a child by another using Command. Nothing else may use stdin or stdout.
+func Stdio() *Conn {
+	c := New(os.Stdin, os.Stdout)
+	c.addr = "stdio"
+	return c
+}
+
+// Command starts cmd with a Conn on its stdin and stdout. The child should use
+// Stdio, or the equivalent in its language. Closing the Conn closes the
+// child's stdin, which should make it exit, and its stdout, so it can't block
+// writing packets nobody will read, then waits for it to exit.
+func Command(cmd *exec.Cmd) (*Conn, error) {
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	return newConn(Addr(cmd.Path), stdout, stdin, func() error {
+		err := stdin.Close()
+		stdout.Close()
+		return errors.Join(err, cmd.Wait())
+	}), nil
+}
+
+func newConn(addr Addr, r io.Reader, w io.Writer, close func() error) *Conn {
+	c := &Conn{
+		w:      w,
+		addr:   addr,
+		close:  close,
+		recv:   make(chan []byte, recvQueue),
+		eof:    make(chan struct{}),
+		closed: make(chan struct{}),
+
+		deadlineChanged: make(chan struct{}),
+	}
+	go c.read(bufio.NewReader(r))
+	return c
+}
+
+// SLIP special bytes.
+const (
+	end    = 0xc0
+	esc    = 0xdb
+	escEnd = 0xdc
+	escEsc = 0xdd
+)
+
+// read decodes packets from r into c.recv until there's an error or the Conn
+// is closed. Packets longer than maxPacket are dropped.
+func (c *Conn) read(r *bufio.Reader) {
+	defer close(c.eof)
+	var p []byte
+	// long is set while skipping the rest of a packet that's too long.
+	long := false
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			c.readErr = err
+			return
+		}
+		switch b {
+		case end:
+			if long {
+				long = false
+				continue
+			}
+			if len(p) == 0 {
+				continue
+			}
+			select {
+			case c.recv <- p:
+			case <-c.closed:
+				return
+			}
+			p = nil
+			continue
+		case esc:
+			if b, err = r.ReadByte(); err != nil {
+				c.readErr = err
+				return
+			}
+			switch b {
+			case escEnd:
+				b = end
+			case escEsc:
+				b = esc
+			}
+		}
+		if long {
+			continue
+		}
+		if len(p) == maxPacket {
+			p, long = nil, true
+			continue
+		}
+		p = append(p, b)
+	}
+}
+
+// ReadFrom reads the next packet. The address is always the Conn's. Once the
+// stream ends it returns the error from reading it, usually io.EOF.
+func (c *Conn) ReadFrom(b []byte) (int, net.Addr, error) {
+	for {
+		c.mu.Lock()
+		deadline, changed := c.readDeadline, c.deadlineChanged
+		c.mu.Unlock()
+		var timeout <-chan time.Time
+		if !deadline.IsZero() {
+			timeout = time.After(time.Until(deadline))
+		}
+		select {
+		case p := <-c.recv:
+			return copy(b, p), c.addr, nil
+		case <-c.closed:
+			return 0, nil, net.ErrClosed
+		case <-c.eof:
+			// Drain packets read before the end.
+			select {
+			case p := <-c.recv:
+				return copy(b, p), c.addr, nil
+			default:
+			}
+			return 0, nil, c.readErr
+		case <-timeout:
+			return 0, nil, os.ErrDeadlineExceeded
+		case <-changed:
+		}
+	}
+}
+
+// WriteTo writes a packet to the stream, blocking until it's written or, if
+// write combining, added to the buffer. The address is ignored.
+func (c *Conn) WriteTo(b []byte, _ net.Addr) (int, error) {
+	select {
+	case <-c.closed:
+		return 0, net.ErrClosed
+	default:
+	}
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	if err := c.werr; err != nil {
+		c.werr = nil
+		return 0, err
+	}
+	c.wbuf = append(c.wbuf, end)
+	for _, x := range b {
+		switch x {
+		case end:
+			c.wbuf = append(c.wbuf, esc, escEnd)
+		case esc:
+			c.wbuf = append(c.wbuf, esc, escEsc)
+		default:
+			c.wbuf = append(c.wbuf, x)
+		}
+	}
+	c.wbuf = append(c.wbuf, end)
+	if len(c.wbuf) >= c.combine {
+		if err := c.flush(); err != nil {
+			return 0, err
+		}
+	} else if c.flushTimer == nil && c.flushAfter > 0 {
+		c.flushTimer = time.AfterFunc(c.flushAfter, func() {
+			c.wmu.Lock()
+			defer c.wmu.Unlock()
+			if err := c.flush(); err != nil {
+				c.werr = err
+			}
+		})
+	}
+	return len(b), nil
+}
+
+// SetWriteCombining combines small packets into fewer writes to the stream:
+// packets are buffered until there are at least size bytes, or the first has
+// waited for maxLatency. If maxLatency is zero, packets wait until the buffer
+// fills or Flush is called. A size of zero, the default, writes every packet
+// straight away. Errors writing combined packets are returned by the next
+// call to WriteTo or Flush.
+func (c *Conn) SetWriteCombining(size int, maxLatency time.Duration) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	c.combine, c.flushAfter = size, maxLatency
+	if len(c.wbuf) >= size {
+		return c.flush()
+	}
+	return nil
+}
+
+// Flush writes any packets buffered by write combining.
+func (c *Conn) Flush() error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	if err := c.werr; err != nil {
+		c.werr = nil
+		return err
+	}
+	return c.flush()
+}
+
+// flush writes wbuf to the stream. c.wmu must be held.
+func (c *Conn) flush() error {
+	if c.flushTimer != nil {
+		c.flushTimer.Stop()
+		c.flushTimer = nil
+	}
+	if len(c.wbuf) == 0 {
+		return nil
+	}
+	_, err := c.w.Write(c.wbuf)
+	c.wbuf = c.wbuf[:0]
+	return err
+}
+
+// SetNoDelay turns Nagle's algorithm off (true, the default in Go) or on for
+// a Conn writing to a TCP connection, letting the kernel combine small
+// packets instead of or as well as SetWriteCombining. It does nothing for
+// other streams.
+func (c *Conn) SetNoDelay(noDelay bool) error {
+	if tcp, ok := c.w.(interface{ SetNoDelay(bool) error }); ok {
+		return tcp.SetNoDelay(noDelay)
+	}
+	return nil
+}
+
+// Close writes any combined packets and closes the streams.
+func (c *Conn) Close() error {
+	err := net.ErrClosed
+	c.once.Do(func() {
+		c.wmu.Lock()
+		flushErr := c.flush()
+		c.wmu.Unlock()
+		close(c.closed)
+		err = errors.Join(flushErr, c.close())
+	})
+	return err
+}
+
+func (c *Conn) LocalAddr() net.Addr { return c.addr }
+
+func (c *Conn) SetDeadline(t time.Time) error {
+	return c.SetReadDeadline(t)
+}
+
+// SetReadDeadline sets the deadline for calls to ReadFrom, including any
+// currently blocked.
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.readDeadline = t
+	close(c.deadlineChanged)
+	c.deadlineChanged = make(chan struct{})
+	return nil
+}
+
+// SetWriteDeadline does nothing, writes block until the other end reads them.
+func (c *Conn) SetWriteDeadline(time.Time) error {
+	return nil
+}
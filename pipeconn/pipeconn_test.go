@@ -0,0 +1,252 @@
+package pipeconn
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"sync"
+	"testing"
+	"time"
+)
+
+// pair returns two Conns connected to each other.
+func pair(t *testing.T) (*Conn, *Conn) {
+	ar, bw := io.Pipe()
+	br, aw := io.Pipe()
+	a, b := New(ar, aw), New(br, bw)
+	t.Cleanup(func() {
+		a.Close()
+		b.Close()
+	})
+	return a, b
+}
+
+func TestConn(t *testing.T) {
+	a, b := pair(t)
+	packets := [][]byte{
+		[]byte("/plain\x00\x00,\x00\x00\x00"),
+		{end, esc, 0, escEnd, escEsc, end},
+		{esc},
+	}
+	go func() {
+		for _, p := range packets {
+			if _, err := a.WriteTo(p, nil); err != nil {
+				t.Errorf("WriteTo(%x): %v", p, err)
+			}
+		}
+	}()
+	buf := make([]byte, 64)
+	for _, want := range packets {
+		b.SetReadDeadline(time.Now().Add(5 * time.Second))
+		n, addr, err := b.ReadFrom(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := buf[:n]; !bytes.Equal(got, want) {
+			t.Errorf("ReadFrom() = %x, want: %x", got, want)
+		}
+		if addr.String() != "pipe" {
+			t.Errorf("ReadFrom() address = %v, want: pipe", addr)
+		}
+	}
+
+	b.SetReadDeadline(time.Now().Add(10 * time.Millisecond))
+	if _, _, err := b.ReadFrom(buf); !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Errorf("ReadFrom() past deadline = %v, want: %v", err, os.ErrDeadlineExceeded)
+	}
+
+	// Closing one end ends the stream for the other.
+	a.Close()
+	b.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, _, err := b.ReadFrom(buf); err != io.EOF {
+		t.Errorf("ReadFrom() after other end closed = %v, want: %v", err, io.EOF)
+	}
+}
+
+func TestCommand(t *testing.T) {
+	if _, err := exec.LookPath("cat"); err != nil {
+		t.Skip("no cat to run")
+	}
+	c, err := Command(exec.Command("cat"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []byte{1, end, 2}
+	if _, err := c.WriteTo(want, nil); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 16)
+	c.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := c.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := buf[:n]; !bytes.Equal(got, want) {
+		t.Errorf("echoed packet = %x, want: %x", got, want)
+	}
+	if err := c.Close(); err != nil {
+		t.Errorf("Close() = %v, want: nil", err)
+	}
+}
+
+func TestLongPacket(t *testing.T) {
+	r, w := io.Pipe()
+	c := New(r, io.Discard)
+	defer c.Close()
+	go func() {
+		w.Write([]byte{end})
+		w.Write(make([]byte, maxPacket+1))
+		w.Write([]byte{end, 'o', 'k', end})
+	}()
+	buf := make([]byte, 16)
+	c.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := c.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(buf[:n]); got != "ok" {
+		t.Errorf("ReadFrom() after a packet over %d bytes = %q, want: %q", maxPacket, got, "ok")
+	}
+}
+
+func TestCommandCloseWhileWriting(t *testing.T) {
+	if _, err := exec.LookPath("yes"); err != nil {
+		t.Skip("no yes to run")
+	}
+	// yes ignores its stdin and writes until stdout is closed.
+	c, err := Command(exec.Command("yes"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan struct{})
+	go func() {
+		c.Close()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close() of a command still writing didn't return")
+	}
+}
+
+// countingWriter counts the writes made to it.
+type countingWriter struct {
+	io.Writer
+	mu     sync.Mutex
+	writes int
+}
+
+func (w *countingWriter) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	w.writes++
+	w.mu.Unlock()
+	return w.Writer.Write(b)
+}
+
+func (w *countingWriter) count() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.writes
+}
+
+func TestWriteCombining(t *testing.T) {
+	ar, bw := io.Pipe()
+	br, aw := io.Pipe()
+	w := &countingWriter{Writer: aw}
+	a, b := New(ar, w), New(br, bw)
+	defer b.Close()
+	buf := make([]byte, 64)
+	read := func(want string) {
+		t.Helper()
+		b.SetReadDeadline(time.Now().Add(5 * time.Second))
+		n, _, err := b.ReadFrom(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := string(buf[:n]); got != want {
+			t.Errorf("ReadFrom() = %q, want: %q", got, want)
+		}
+	}
+
+	// Nothing is written until Flush.
+	a.SetWriteCombining(1024, 0)
+	for _, p := range []string{"one", "two", "three"} {
+		a.WriteTo([]byte(p), nil)
+	}
+	b.SetReadDeadline(time.Now().Add(10 * time.Millisecond))
+	if _, _, err := b.ReadFrom(buf); !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Errorf("ReadFrom() before Flush = %v, want: %v", err, os.ErrDeadlineExceeded)
+	}
+	if err := a.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	read("one")
+	read("two")
+	read("three")
+	if got := w.count(); got != 1 {
+		t.Errorf("combined 3 packets into %d writes, want: 1", got)
+	}
+
+	// The buffer is written once it's full.
+	a.SetWriteCombining(10, 0)
+	a.WriteTo([]byte("four"), nil)
+	a.WriteTo([]byte("five"), nil)
+	read("four")
+	read("five")
+
+	// Or after the latency bound.
+	a.SetWriteCombining(1024, 10*time.Millisecond)
+	a.WriteTo([]byte("six"), nil)
+	read("six")
+
+	// Close writes anything left.
+	a.SetWriteCombining(1024, 0)
+	a.WriteTo([]byte("seven"), nil)
+	a.Close()
+	read("seven")
+	if got := w.count(); got != 4 {
+		t.Errorf("got %d writes, want: 4", got)
+	}
+}
+
+func TestTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	accepted := make(chan net.Conn)
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			t.Error(err)
+		}
+		accepted <- c
+	}()
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := New(conn, conn)
+	defer a.Close()
+	other := <-accepted
+	b := New(other, other)
+	defer b.Close()
+
+	if err := a.SetNoDelay(false); err != nil {
+		t.Fatalf("SetNoDelay(false) = %v", err)
+	}
+	if _, err := a.WriteTo([]byte("/tcp"), nil); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 16)
+	b.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := b.ReadFrom(buf)
+	if err != nil || string(buf[:n]) != "/tcp" {
+		t.Errorf("ReadFrom() = %q, %v, want: /tcp, nil", buf[:n], err)
+	}
+}
@@ -0,0 +1,40 @@
+package osc
+
+import "sync"
+
+var messagePool = sync.Pool{
+	New: func() any {
+		return new(Message)
+	},
+}
+
+// AcquireMessage returns an empty Message, which may be reused from an earlier
+// call to ReleaseMessage. Together with ParseMessageInto, this avoids
+// allocating for every message received.
+func AcquireMessage() *Message {
+	return messagePool.Get().(*Message)
+}
+
+// ReleaseMessage resets a message and returns it to the pool used by
+// AcquireMessage. Neither the message nor its arguments may be used
+// afterwards.
+func ReleaseMessage(m *Message) {
+	m.Reset()
+	messagePool.Put(m)
+}
+
+var bufPool = sync.Pool{
+	New: func() any {
+		b := make([]byte, 1024)
+		return &b
+	},
+}
+
+func getBuf() []byte {
+	b := bufPool.Get().(*[]byte)
+	return (*b)[:0]
+}
+
+func putBuf(b []byte) {
+	bufPool.Put(&b)
+}
@@ -0,0 +1,90 @@
+package osc
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// PreparedMessage is a message with a fixed address and type tags, encoded once
+// up front, so that sending it with new values only writes the arguments in
+// place. It's for the high rate messages with a fixed shape, such as a fader's
+// "/fader f", that dominate many programs. Only fixed size arguments are
+// supported: int32, float32, time tags and the types with no data.
+type PreparedMessage struct {
+	buf  []byte
+	tags string
+	// offsets holds where each argument starts in buf.
+	offsets []int
+}
+
+// Prepare returns a PreparedMessage for address and the type tags (without the
+// leading ','). Its arguments start out as zero.
+func Prepare(address, typeTags string) (*PreparedMessage, error) {
+	msg := Message{Pattern: address}
+	for _, t := range typeTags {
+		switch t {
+		case 'i':
+			msg.Arguments = append(msg.Arguments, new(Int32))
+		case 'f':
+			msg.Arguments = append(msg.Arguments, new(Float32))
+		case 't':
+			msg.Arguments = append(msg.Arguments, new(TimeTag))
+		case 'T':
+			msg.Arguments = append(msg.Arguments, True{})
+		case 'F':
+			msg.Arguments = append(msg.Arguments, False{})
+		case 'N':
+			msg.Arguments = append(msg.Arguments, Null{})
+		case 'I':
+			msg.Arguments = append(msg.Arguments, Impulse{})
+		default:
+			return nil, fmt.Errorf("expect fixed size type tag, got: %q", t)
+		}
+	}
+	p := &PreparedMessage{buf: msg.Append(nil), tags: typeTags}
+	// The arguments come after the address and type tags.
+	off := String(address).EncodedSize() + String(","+typeTags).EncodedSize()
+	for _, a := range msg.Arguments {
+		p.offsets = append(p.offsets, off)
+		off += a.(Sizer).EncodedSize()
+	}
+	return p, nil
+}
+
+// check panics if argument i isn't of type t.
+func (p *PreparedMessage) check(i int, t byte) {
+	if p.tags[i] != t {
+		panic(fmt.Sprintf("osc: argument %d of prepared message has type %c, not %c", i, p.tags[i], t))
+	}
+}
+
+// SetInt32 sets argument i, which must be an int32.
+func (p *PreparedMessage) SetInt32(i int, v int32) {
+	p.check(i, 'i')
+	binary.BigEndian.PutUint32(p.buf[p.offsets[i]:], uint32(v))
+}
+
+// SetFloat32 sets argument i, which must be a float32.
+func (p *PreparedMessage) SetFloat32(i int, v float32) {
+	p.check(i, 'f')
+	binary.BigEndian.PutUint32(p.buf[p.offsets[i]:], math.Float32bits(v))
+}
+
+// SetTimeTag sets argument i, which must be a time tag.
+func (p *PreparedMessage) SetTimeTag(i int, t TimeTag) {
+	p.SetRawTimeTag(i, t.Raw())
+}
+
+// SetRawTimeTag sets argument i, which must be a time tag, to an NTP time as
+// returned by TimeTag.Raw or AddRaw.
+func (p *PreparedMessage) SetRawTimeTag(i int, raw uint64) {
+	p.check(i, 't')
+	binary.BigEndian.PutUint64(p.buf[p.offsets[i]:], raw)
+}
+
+// Bytes returns the encoded message. It's overwritten by the next call to a
+// Set method, so must not be kept.
+func (p *PreparedMessage) Bytes() []byte {
+	return p.buf
+}
@@ -0,0 +1,38 @@
+package osc
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestPreparedMessage(t *testing.T) {
+	p, err := Prepare("/mix/fader", "iTft")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := TimeTag{time.Unix(1700000000, 0)}
+	for _, v := range []float32{0, 0.5, 1} {
+		p.SetInt32(0, 7)
+		p.SetFloat32(2, v)
+		p.SetTimeTag(3, now)
+		f := Float32(v)
+		want := Message{Pattern: "/mix/fader", Arguments: []Argument{AsInt32(7), True{}, &f, &now}}.Append(nil)
+		if got := p.Bytes(); !bytes.Equal(got, want) {
+			t.Errorf("prepared message with %v = %x, want: %x", v, got, want)
+		}
+	}
+	if allocs := testing.AllocsPerRun(100, func() { p.SetFloat32(2, 0.25) }); allocs != 0 {
+		t.Errorf("SetFloat32 allocated %.1f times, want: 0", allocs)
+	}
+
+	if _, err := Prepare("/name", "s"); err == nil {
+		t.Error(`Prepare("/name", "s") = nil error, want error`)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("SetInt32 on a float32 argument didn't panic")
+		}
+	}()
+	p.SetInt32(2, 1)
+}
@@ -0,0 +1,7 @@
+//go:build race
+
+package osc
+
+// raceEnabled is true when built with the race detector, which makes
+// allocation counts unreliable.
+const raceEnabled = true
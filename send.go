@@ -0,0 +1,84 @@
+//go:build !tinygo
+
+package osc
+
+import (
+	"context"
+	"net"
+	"net/netip"
+)
+
+// Send builds and sends a message using the provided arguments, to the given
+// pattern at the provided address.
+// TODO: not a great api?
+func Send(conn net.PacketConn, addr, pattern string, args ...Argument) error {
+	return SendContext(context.Background(), conn, addr, pattern, args...)
+}
+
+// SendContext is like Send, but gives up if the context is done while
+// resolving the address or writing the message. Writes to stream transports
+// such as pipeconn block until the peer reads them; if the context is done
+// first, SendContext returns its error and leaves the write to finish or fail
+// in the background. conn may be shared with other writers, such as a
+// Listener replying to peers, so its deadlines are left alone.
+func SendContext(ctx context.Context, conn net.PacketConn, addr, pattern string, args ...Argument) error {
+	nAddr, err := resolveUDP(ctx, addr)
+	if err != nil {
+		return err
+	}
+	msg := Message{
+		Pattern:   pattern,
+		Arguments: args,
+	}
+	b := msg.Append(getBuf())
+	if err := ctx.Err(); err != nil {
+		putBuf(b)
+		return err
+	}
+	if ctx.Done() == nil {
+		// Can't be cancelled, so don't pay for a goroutine.
+		_, err = conn.WriteTo(b, nAddr)
+		putBuf(b)
+		return err
+	}
+	done := make(chan error, 1)
+	go func() {
+		_, err := conn.WriteTo(b, nAddr)
+		putBuf(b)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// resolveUDP is net.ResolveUDPAddr, but using the context for lookups.
+func resolveUDP(ctx context.Context, addr string) (*net.UDPAddr, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	p, err := net.DefaultResolver.LookupPort(ctx, "udp", port)
+	if err != nil {
+		return nil, err
+	}
+	if host == "" {
+		return &net.UDPAddr{Port: p}, nil
+	}
+	ips, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return nil, err
+	}
+	// Prefer IPv4, as net.ResolveUDPAddr does.
+	ip := ips[0]
+	for _, a := range ips {
+		if a.Unmap().Is4() {
+			ip = a
+			break
+		}
+	}
+	return net.UDPAddrFromAddrPort(netip.AddrPortFrom(ip.Unmap(), uint16(p))), nil
+}
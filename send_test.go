@@ -0,0 +1,85 @@
+//go:build !tinygo
+
+package osc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/pfcm/osc/pipeconn"
+)
+
+func TestSendContext(t *testing.T) {
+	recv, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer recv.Close()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	port := strconv.Itoa(recv.LocalAddr().(*net.UDPAddr).Port)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := SendContext(ctx, conn, "localhost:"+port, "/ctx", AsInt32(1)); err != nil {
+		t.Fatal(err)
+	}
+	recv.SetReadDeadline(time.Now().Add(5 * time.Second))
+	buf := make([]byte, 64)
+	n, _, err := recv.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg, err := ParseMessage(buf[:n]); err != nil || msg.Pattern != "/ctx" {
+		t.Errorf("received %v, %v, want: /ctx", msg, err)
+	}
+
+	cancel()
+	if err := SendContext(ctx, conn, "127.0.0.1:"+port, "/ctx"); !errors.Is(err, context.Canceled) {
+		t.Errorf("SendContext(cancelled) = %v, want: %v", err, context.Canceled)
+	}
+	// The deadline doesn't outlive the call.
+	if err := Send(conn, "127.0.0.1:"+port, "/after"); err != nil {
+		t.Errorf("Send after SendContext = %v, want: nil", err)
+	}
+
+	// Deadlines set by other users of the conn are left alone.
+	conn.SetWriteDeadline(time.Now().Add(-time.Second))
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := SendContext(ctx, conn, "127.0.0.1:"+port, "/late"); !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Errorf("SendContext past the conn's deadline = %v, want: %v", err, os.ErrDeadlineExceeded)
+	}
+	conn.SetWriteDeadline(time.Time{})
+}
+
+func TestSendContextBlocked(t *testing.T) {
+	// Nobody reads from the other end of the pipe, so writes block.
+	r, _ := io.Pipe()
+	peer, w := io.Pipe()
+	conn := pipeconn.New(r, w)
+	defer conn.Close()
+	// Fail the abandoned write, so Close doesn't wait for it.
+	defer peer.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	done := make(chan error)
+	go func() { done <- SendContext(ctx, conn, "127.0.0.1:1", "/blocked") }()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("SendContext to a blocked pipe = %v, want: %v", err, context.DeadlineExceeded)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("SendContext to a blocked pipe didn't return after the context was done")
+	}
+}
@@ -0,0 +1,49 @@
+package osc
+
+import "sync"
+
+// Sequencer adds sequence numbers to outgoing messages, so receivers can
+// detect lost messages. The sequence number is an Int32 appended as the final
+// argument of each message. Sequences either run across every message stamped
+// by the Sequencer, or separately for each address.
+type Sequencer struct {
+	perAddress bool
+
+	mu   sync.Mutex
+	next map[string]int32
+}
+
+// NewSequencer returns a Sequencer, with a separate sequence for each address
+// if perAddress is true.
+func NewSequencer(perAddress bool) *Sequencer {
+	return &Sequencer{
+		perAddress: perAddress,
+		next:       make(map[string]int32),
+	}
+}
+
+// Stamp appends the next sequence number to the message's arguments.
+func (s *Sequencer) Stamp(msg *Message) {
+	var key string
+	if s.perAddress {
+		key = msg.Pattern
+	}
+	s.mu.Lock()
+	seq := s.next[key]
+	s.next[key] = seq + 1
+	s.mu.Unlock()
+	msg.Arguments = append(msg.Arguments, AsInt32(seq))
+}
+
+// SequenceNumber returns the sequence number added to a message by a
+// Sequencer, and whether it had one.
+func SequenceNumber(msg *Message) (int32, bool) {
+	if len(msg.Arguments) == 0 {
+		return 0, false
+	}
+	i, ok := msg.Arguments[len(msg.Arguments)-1].(*Int32)
+	if !ok {
+		return 0, false
+	}
+	return int32(*i), true
+}
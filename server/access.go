@@ -0,0 +1,98 @@
+package server
+
+import (
+	"net"
+	"net/netip"
+	"strings"
+)
+
+// Access controls which address subtrees each peer may send messages to. A
+// subtree is an address or pattern, such as "/monitor" or "/mix/[1-4]", and
+// covers every address it matches as well as everything below them. Peers are
+// denied anything they haven't been allowed, so a Listener with an empty Access
+// dispatches nothing.
+//
+// Access is checked against the address of each handler a message would be
+// dispatched to, so a guest sending "/*/level" only reaches the handlers in
+// their subtrees.
+type Access struct {
+	grants []grant
+}
+
+type grant struct {
+	peers   netip.Prefix
+	subtree string
+}
+
+// Allow lets peers in the prefix send to the provided subtrees. Like Handle,
+// it shouldn't be called while the Listener is serving.
+func (a *Access) Allow(peers netip.Prefix, subtrees ...string) error {
+	for _, s := range subtrees {
+		if err := validate(s); err != nil {
+			return err
+		}
+	}
+	for _, s := range subtrees {
+		a.grants = append(a.grants, grant{peers.Masked(), strings.TrimSuffix(s, "/")})
+	}
+	return nil
+}
+
+// Allowed reports whether the peer at addr may send to address.
+func (a *Access) Allowed(addr net.Addr, address string) bool {
+	ip, ok := peerIP(addr)
+	if !ok {
+		return false
+	}
+	for _, g := range a.grants {
+		if g.peers.Contains(ip) && inSubtree(g.subtree, address) {
+			return true
+		}
+	}
+	return false
+}
+
+// peerIP returns the IP address of a peer, if it has one.
+func peerIP(addr net.Addr) (netip.Addr, bool) {
+	if u, ok := addr.(*net.UDPAddr); ok {
+		ip, ok := netip.AddrFromSlice(u.IP)
+		return ip.Unmap(), ok
+	}
+	if addr == nil {
+		return netip.Addr{}, false
+	}
+	ap, err := netip.ParseAddrPort(addr.String())
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return ap.Addr().Unmap(), true
+}
+
+// inSubtree reports whether address is matched by subtree, or is below an
+// address that is. An empty subtree is the root, which contains everything.
+func inSubtree(subtree, address string) bool {
+	// Compare the subtree against the same number of components from the
+	// start of the address.
+	n := strings.Count(subtree, "/")
+	end := 0
+	for range n {
+		if end >= len(address) {
+			return false
+		}
+		i := strings.IndexByte(address[end+1:], '/')
+		if i < 0 {
+			end = len(address)
+			break
+		}
+		end += i + 1
+	}
+	return match(subtree, address[:end])
+}
+
+// WithAccess only dispatches messages to handlers the sender is allowed to
+// reach by a.
+func WithAccess(a *Access) Option {
+	return func(l *Listener) {
+		l.access = a
+	}
+}
@@ -0,0 +1,74 @@
+package server
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"slices"
+	"testing"
+
+	"github.com/pfcm/osc"
+)
+
+func TestInSubtree(t *testing.T) {
+	for _, c := range []struct {
+		subtree, address string
+		want             bool
+	}{
+		{"", "/anything/at/all", true},
+		{"/monitor", "/monitor", true},
+		{"/monitor", "/monitor/1/level", true},
+		{"/monitor", "/monitors/1/level", false},
+		{"/monitor", "/master/level", false},
+		{"/mix/[1-4]", "/mix/3/level", true},
+		{"/mix/[1-4]", "/mix/5/level", false},
+		{"/mix/[1-4]", "/mix", false},
+		{"/mix/*/mute", "/mix/2/mute", true},
+		{"/monitor", "", false},
+	} {
+		if got := inSubtree(c.subtree, c.address); got != c.want {
+			t.Errorf("inSubtree(%q, %q) = %t, want: %t", c.subtree, c.address, got, c.want)
+		}
+	}
+}
+
+func TestAccess(t *testing.T) {
+	var a Access
+	if err := a.Allow(netip.MustParsePrefix("10.0.0.0/24"), "/monitor"); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Allow(netip.MustParsePrefix("10.0.0.1/32"), "/"); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Allow(netip.MustParsePrefix("10.0.0.1/32"), "/[oops"); err == nil {
+		t.Error("Allow() with invalid subtree = nil, want error")
+	}
+
+	l := NewListener(nil, 1, WithAccess(&a))
+	var got []string
+	for _, p := range []string{"/monitor/1/level", "/monitor/2/level", "/master/level"} {
+		l.Handle(p, HandlerFunc(func(*osc.Message) error {
+			got = append(got, p)
+			return nil
+		}))
+	}
+	for _, c := range []struct {
+		peer string
+		want []string
+	}{
+		{"10.0.0.1:9000", []string{"/monitor/1/level", "/monitor/2/level", "/master/level"}},
+		{"10.0.0.2:9000", []string{"/monitor/1/level", "/monitor/2/level"}},
+		{"[::ffff:10.0.0.2]:9000", []string{"/monitor/1/level", "/monitor/2/level"}},
+		{"10.0.1.1:9000", nil},
+	} {
+		got = nil
+		addr, err := net.ResolveUDPAddr("udp", c.peer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		l.handle(context.Background(), packet{&osc.Message{Pattern: "/*/level"}, Meta{Addr: addr}})
+		if !slices.Equal(got, c.want) {
+			t.Errorf("message from %v reached %v, want: %v", c.peer, got, c.want)
+		}
+	}
+}
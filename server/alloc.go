@@ -0,0 +1,114 @@
+package server
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Allocator hands out address subtrees for dynamically created entities, such
+// as "/voices/3" for a synth voice, registering the entity's handlers on a
+// Listener and removing them when it's freed. Freed ids are reused, lowest
+// first, so addresses stay small for clients like control surfaces. It's safe
+// for concurrent use, including while the Listener is serving.
+type Allocator struct {
+	l      *Listener
+	prefix string
+
+	mu sync.Mutex
+	// next is the lowest id never allocated, and free holds ids below it
+	// that have been freed.
+	next int
+	free []int
+	// live holds the addresses registered for each allocated id.
+	live map[int][]string
+}
+
+// NewAllocator returns an Allocator for subtrees of prefix, such as "/voices",
+// on l.
+func NewAllocator(l *Listener, prefix string) *Allocator {
+	return &Allocator{
+		l:      l,
+		prefix: strings.TrimSuffix(prefix, "/"),
+		live:   make(map[int][]string),
+	}
+}
+
+// Alloc allocates an id, and registers each handler on its address relative to
+// the id's subtree: a handler with Address "/freq" is registered on
+// "/voices/3/freq". It returns the id and the subtree's address. Ids whose
+// subtree already has handlers on the Listener, registered by something else,
+// are skipped.
+func (a *Allocator) Alloc(handlers ...RegisteredHandler) (int, string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var id int
+	for {
+		if len(a.free) > 0 {
+			i := slices.Index(a.free, slices.Min(a.free))
+			id = a.free[i]
+			a.free = slices.Delete(a.free, i, i+1)
+		} else {
+			id = a.next
+			a.next++
+		}
+		if !a.inUse(id) {
+			break
+		}
+	}
+	root := a.Address(id)
+	addrs := make([]string, len(handlers))
+	for i, h := range handlers {
+		addrs[i] = root + h.Address
+		a.l.Handle(addrs[i], h.Handler)
+	}
+	a.live[id] = addrs
+	return id, root
+}
+
+// inUse reports whether the Listener has handlers in id's subtree.
+func (a *Allocator) inUse(id int) bool {
+	root := a.Address(id)
+	for _, h := range a.l.handlerTable() {
+		if h.p == root || strings.HasPrefix(h.p, root+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// Free unregisters the handlers of an allocated id, and makes it available to
+// Alloc again.
+func (a *Allocator) Free(id int) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	addrs, ok := a.live[id]
+	if !ok {
+		return fmt.Errorf("%s: id %d isn't allocated", a.prefix, id)
+	}
+	for _, addr := range addrs {
+		a.l.Unhandle(addr)
+	}
+	delete(a.live, id)
+	a.free = append(a.free, id)
+	return nil
+}
+
+// Address returns the address of id's subtree.
+func (a *Allocator) Address(id int) string {
+	return a.prefix + "/" + strconv.Itoa(id)
+}
+
+// Allocated returns the allocated ids, in order.
+func (a *Allocator) Allocated() []int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	ids := make([]int, 0, len(a.live))
+	for id := range a.live {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids
+}
@@ -0,0 +1,78 @@
+package server
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/pfcm/osc"
+)
+
+func TestAllocator(t *testing.T) {
+	l := NewListener(nil, 1)
+	// Something else already owns /voices/1.
+	l.Handle("/voices/1/freq", HandlerFunc(func(*osc.Message) error { return nil }))
+	a := NewAllocator(l, "/voices/")
+
+	var got []string
+	voice := func(name string) []RegisteredHandler {
+		return []RegisteredHandler{{"/freq", HandlerFunc(func(msg *osc.Message) error {
+			got = append(got, name)
+			return nil
+		})}}
+	}
+	for i, want := range []struct {
+		id   int
+		addr string
+	}{{0, "/voices/0"}, {2, "/voices/2"}, {3, "/voices/3"}} {
+		id, addr := a.Alloc(voice(string(rune('a' + i)))...)
+		if id != want.id || addr != want.addr {
+			t.Errorf("Alloc() = %d, %q, want: %d, %q", id, addr, want.id, want.addr)
+		}
+	}
+	dispatch := func(address string) {
+		l.handle(context.Background(), packet{msg: &osc.Message{Pattern: address}})
+	}
+	dispatch("/voices/*/freq")
+	if want := []string{"a", "b", "c"}; !slices.Equal(got, want) {
+		t.Errorf("handled by %v, want: %v", got, want)
+	}
+
+	if err := a.Free(2); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Free(2); err == nil {
+		t.Error("Free() twice = nil error, want error")
+	}
+	got = nil
+	dispatch("/voices/2/freq")
+	if len(got) > 0 {
+		t.Errorf("freed voice handled message, by %v", got)
+	}
+	if ids := a.Allocated(); !slices.Equal(ids, []int{0, 3}) {
+		t.Errorf("Allocated() = %v, want: [0 3]", ids)
+	}
+	// The lowest free id is reused.
+	if id, _ := a.Alloc(voice("d")...); id != 2 {
+		t.Errorf("Alloc() after Free(2) = %d, want: 2", id)
+	}
+	if id, _ := a.Alloc(voice("e")...); id != 4 {
+		t.Errorf("Alloc() = %d, want: 4", id)
+	}
+}
+
+func TestUnhandle(t *testing.T) {
+	l := NewListener(nil, 1)
+	for _, p := range []string{"/a", "/b", "/a"} {
+		l.Handle(p, HandlerFunc(func(*osc.Message) error { return nil }))
+	}
+	if !l.Unhandle("/a") {
+		t.Error(`Unhandle("/a") = false, want: true`)
+	}
+	if l.Unhandle("/a") {
+		t.Error(`Unhandle("/a") again = true, want: false`)
+	}
+	if m := l.MatchesFor("/*"); len(m) != 1 || m[0].Address != "/b" {
+		t.Errorf("MatchesFor(/*) after Unhandle = %v, want just /b", m)
+	}
+}
@@ -0,0 +1,206 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pfcm/osc"
+)
+
+// Arg is a Go type a message argument can be decoded into by the HandleArgs
+// functions. bool is decoded from True and False, time.Time from a time tag,
+// int64 from either integer type, float64 from either float type and string
+// from a String or Symbol. A []byte shares memory with the message's blob.
+// Other argument types can be converted with WithCoercion.
+type Arg interface {
+	int32 | int64 | float32 | float64 | string | []byte | bool | time.Time
+}
+
+// HandleArgs0 returns a handler calling f for messages with no arguments.
+func HandleArgs0(f func(context.Context) error) ContextHandler {
+	return ContextHandlerFunc(func(ctx context.Context, msg *osc.Message) error {
+		if err := checkArgs(msg, 0); err != nil {
+			return err
+		}
+		return f(ctx)
+	})
+}
+
+// HandleArgs1 returns a handler calling f with the argument of messages with
+// exactly one argument of type A. Messages with any other arguments are an
+// error. Unlike decoding with reflection, this doesn't allocate.
+func HandleArgs1[A Arg](f func(context.Context, A) error) ContextHandler {
+	return ContextHandlerFunc(func(ctx context.Context, msg *osc.Message) error {
+		var a A
+		co := coercionFromContext(ctx)
+		if err := checkArgs(msg, 1); err != nil {
+			return err
+		}
+		if err := decodeArg(co, msg, 0, &a); err != nil {
+			return err
+		}
+		return f(ctx, a)
+	})
+}
+
+// HandleArgs2 is like HandleArgs1, for messages with two arguments.
+func HandleArgs2[A, B Arg](f func(context.Context, A, B) error) ContextHandler {
+	return ContextHandlerFunc(func(ctx context.Context, msg *osc.Message) error {
+		var (
+			a A
+			b B
+		)
+		co := coercionFromContext(ctx)
+		if err := checkArgs(msg, 2); err != nil {
+			return err
+		}
+		if err := decodeArg(co, msg, 0, &a); err != nil {
+			return err
+		}
+		if err := decodeArg(co, msg, 1, &b); err != nil {
+			return err
+		}
+		return f(ctx, a, b)
+	})
+}
+
+// HandleArgs3 is like HandleArgs1, for messages with three arguments.
+func HandleArgs3[A, B, C Arg](f func(context.Context, A, B, C) error) ContextHandler {
+	return ContextHandlerFunc(func(ctx context.Context, msg *osc.Message) error {
+		var (
+			a A
+			b B
+			c C
+		)
+		co := coercionFromContext(ctx)
+		if err := checkArgs(msg, 3); err != nil {
+			return err
+		}
+		if err := decodeArg(co, msg, 0, &a); err != nil {
+			return err
+		}
+		if err := decodeArg(co, msg, 1, &b); err != nil {
+			return err
+		}
+		if err := decodeArg(co, msg, 2, &c); err != nil {
+			return err
+		}
+		return f(ctx, a, b, c)
+	})
+}
+
+// HandleArgs4 is like HandleArgs1, for messages with four arguments.
+func HandleArgs4[A, B, C, D Arg](f func(context.Context, A, B, C, D) error) ContextHandler {
+	return ContextHandlerFunc(func(ctx context.Context, msg *osc.Message) error {
+		var (
+			a A
+			b B
+			c C
+			d D
+		)
+		co := coercionFromContext(ctx)
+		if err := checkArgs(msg, 4); err != nil {
+			return err
+		}
+		if err := decodeArg(co, msg, 0, &a); err != nil {
+			return err
+		}
+		if err := decodeArg(co, msg, 1, &b); err != nil {
+			return err
+		}
+		if err := decodeArg(co, msg, 2, &c); err != nil {
+			return err
+		}
+		if err := decodeArg(co, msg, 3, &d); err != nil {
+			return err
+		}
+		return f(ctx, a, b, c, d)
+	})
+}
+
+func checkArgs(msg *osc.Message, n int) error {
+	if len(msg.Arguments) != n {
+		return fmt.Errorf("expect %d arguments, got: %d (%q)", n, len(msg.Arguments), msg.TypeTag())
+	}
+	return nil
+}
+
+// decodeArg decodes argument i of msg into p, making the conversions in c. The
+// type switch is on the pointer, so no reflection is needed.
+func decodeArg[T Arg](c Coercion, msg *osc.Message, i int, p *T) error {
+	arg := msg.Arguments[i]
+	ok := false
+	switch p := any(p).(type) {
+	case *int32:
+		switch v := arg.(type) {
+		case *osc.Int32:
+			*p, ok = int32(*v), true
+		case *osc.Float32:
+			*p, ok = int32(*v), c&FloatInts != 0
+		case osc.True:
+			*p, ok = 1, c&BoolNumbers != 0
+		case osc.False:
+			*p, ok = 0, c&BoolNumbers != 0
+		}
+	case *int64:
+		switch v := arg.(type) {
+		case *osc.Int64:
+			*p, ok = int64(*v), true
+		case *osc.Int32:
+			*p, ok = int64(*v), true
+		}
+	case *float32:
+		switch v := arg.(type) {
+		case *osc.Float32:
+			*p, ok = float32(*v), true
+		case *osc.Int32:
+			*p, ok = float32(*v), c&IntFloats != 0
+		case osc.True:
+			*p, ok = 1, c&BoolNumbers != 0
+		case osc.False:
+			*p, ok = 0, c&BoolNumbers != 0
+		}
+	case *float64:
+		switch v := arg.(type) {
+		case *osc.Float64:
+			*p, ok = float64(*v), true
+		case *osc.Float32:
+			*p, ok = float64(*v), true
+		case *osc.Int32:
+			*p, ok = float64(*v), c&IntFloats != 0
+		}
+	case *string:
+		switch v := arg.(type) {
+		case *osc.String:
+			*p, ok = string(*v), true
+		case *osc.Symbol:
+			*p, ok = string(*v), true
+		}
+	case *[]byte:
+		var v *osc.Blob
+		if v, ok = arg.(*osc.Blob); ok {
+			*p = []byte(*v)
+		}
+	case *bool:
+		switch v := arg.(type) {
+		case osc.True:
+			*p, ok = true, true
+		case osc.False:
+			*p, ok = false, true
+		case *osc.Int32:
+			*p, ok = *v != 0, c&IntBools != 0
+		case *osc.Float32:
+			*p, ok = *v != 0, c&FloatBools != 0
+		}
+	case *time.Time:
+		var v *osc.TimeTag
+		if v, ok = arg.(*osc.TimeTag); ok {
+			*p = v.Time
+		}
+	}
+	if !ok {
+		return fmt.Errorf("argument %d: expect %T, got: %v", i, *p, arg)
+	}
+	return nil
+}
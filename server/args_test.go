@@ -0,0 +1,138 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pfcm/osc"
+)
+
+func TestHandleArgs(t *testing.T) {
+	f := osc.Float32(0.5)
+	blob := osc.Blob("data")
+	now := time.Unix(1700000000, 0)
+	msg := &osc.Message{Pattern: "/args", Arguments: []osc.Argument{
+		osc.AsInt32(3), &f, osc.AsString("name"), osc.True{},
+	}}
+	var got []any
+	h := HandleArgs4(func(_ context.Context, i int32, f float32, s string, b bool) error {
+		got = append(got, i, f, s, b)
+		return nil
+	})
+	if err := h.HandleContext(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+	if want := []any{int32(3), float32(0.5), "name", true}; len(got) != len(want) ||
+		got[0] != want[0] || got[1] != want[1] || got[2] != want[2] || got[3] != want[3] {
+		t.Errorf("HandleArgs4 called with %v, want: %v", got, want)
+	}
+	h = HandleArgs4(func(context.Context, int32, float32, string, bool) error { return nil })
+	if allocs := testing.AllocsPerRun(100, func() { h.HandleContext(context.Background(), msg) }); allocs != 0 {
+		t.Errorf("HandleArgs4 allocated %.1f times, want: 0", allocs)
+	}
+
+	var gotBlob []byte
+	var gotTime time.Time
+	h2 := HandleArgs2(func(_ context.Context, b []byte, t time.Time) error {
+		gotBlob, gotTime = b, t
+		return nil
+	})
+	if err := h2.Handle(&osc.Message{Pattern: "/args", Arguments: []osc.Argument{&blob, &osc.TimeTag{Time: now}}}); err != nil {
+		t.Fatal(err)
+	}
+	if string(gotBlob) != "data" || !gotTime.Equal(now) {
+		t.Errorf("HandleArgs2 called with %q, %v, want: %q, %v", gotBlob, gotTime, "data", now)
+	}
+
+	var got64 []int64
+	h2 = HandleArgs2(func(_ context.Context, a, b int64) error {
+		got64 = append(got64, a, b)
+		return nil
+	})
+	if err := h2.Handle(&osc.Message{Pattern: "/args", Arguments: []osc.Argument{osc.AsInt64(1 << 40), osc.AsInt32(-7)}}); err != nil {
+		t.Fatal(err)
+	}
+	if len(got64) != 2 || got64[0] != 1<<40 || got64[1] != -7 {
+		t.Errorf("HandleArgs2 called with %v, want: [%d -7]", got64, int64(1<<40))
+	}
+
+	called := false
+	h0 := HandleArgs0(func(context.Context) error {
+		called = true
+		return nil
+	})
+	if err := h0.Handle(&osc.Message{Pattern: "/go"}); err != nil || !called {
+		t.Errorf("HandleArgs0 = %v, called: %v, want: nil, true", err, called)
+	}
+
+	for _, c := range []struct {
+		name string
+		h    Handler
+		args []osc.Argument
+	}{
+		{"too few", HandleArgs1(func(context.Context, int32) error { return nil }), nil},
+		{"too many", HandleArgs0(func(context.Context) error { return nil }), []osc.Argument{osc.AsInt32(1)}},
+		{"wrong type", HandleArgs1(func(context.Context, int32) error { return nil }), []osc.Argument{&f}},
+		{"int32 from int64", HandleArgs1(func(context.Context, int32) error { return nil }), []osc.Argument{osc.AsInt64(1)}},
+		{"not bool", HandleArgs1(func(context.Context, bool) error { return nil }), []osc.Argument{osc.Null{}}},
+		{"third wrong", HandleArgs3(func(context.Context, int32, int32, string) error { return nil }),
+			[]osc.Argument{osc.AsInt32(1), osc.AsInt32(2), osc.AsInt32(3)}},
+	} {
+		if err := c.h.Handle(&osc.Message{Pattern: "/args", Arguments: c.args}); err == nil {
+			t.Errorf("handler with %s arguments = nil, want error", c.name)
+		}
+	}
+}
+
+func TestCoercion(t *testing.T) {
+	f := osc.Float32(2.75)
+	zero := osc.Float32(0)
+	// Each case decodes arg into the type of want, or into into if
+	// decoding should fail.
+	for _, c := range []struct {
+		coercion Coercion
+		arg      osc.Argument
+		into     any
+		want     any
+	}{
+		{0, osc.AsInt32(1), false, nil},
+		{IntBools, osc.AsInt32(1), nil, true},
+		{IntBools, osc.AsInt32(0), nil, false},
+		{IntBools, &f, false, nil},
+		{FloatBools, &zero, nil, false},
+		{FloatBools, &f, nil, true},
+		{FloatInts, &f, nil, int32(2)},
+		{0, &f, int32(0), nil},
+		{IntFloats, osc.AsInt32(3), nil, float32(3)},
+		{BoolNumbers, osc.True{}, nil, int32(1)},
+		{BoolNumbers, osc.False{}, nil, float32(0)},
+		{Lenient, osc.AsString("1"), false, nil},
+		{0, &f, nil, float64(2.75)},
+		{0, osc.AsInt32(3), float64(0), nil},
+		{IntFloats, osc.AsInt32(3), nil, float64(3)},
+	} {
+		into := c.into
+		if into == nil {
+			into = c.want
+		}
+		var got any
+		var h Handler
+		switch into.(type) {
+		case int32:
+			h = HandleArgs1(func(_ context.Context, v int32) error { got = v; return nil })
+		case float32:
+			h = HandleArgs1(func(_ context.Context, v float32) error { got = v; return nil })
+		case float64:
+			h = HandleArgs1(func(_ context.Context, v float64) error { got = v; return nil })
+		case bool:
+			h = HandleArgs1(func(_ context.Context, v bool) error { got = v; return nil })
+		}
+		l := NewListener(nil, 1, WithCoercion(c.coercion))
+		l.Handle("/c", h)
+		l.handle(context.Background(), packet{msg: &osc.Message{Pattern: "/c", Arguments: []osc.Argument{c.arg}}})
+		if got != c.want {
+			t.Errorf("coercion %b of %v into %T = %v, want: %v", c.coercion, c.arg, into, got, c.want)
+		}
+	}
+}
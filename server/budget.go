@@ -0,0 +1,117 @@
+package server
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/pfcm/osc"
+)
+
+// Overrun describes a handler call that took longer than its budget, reported
+// by Budgeted.
+type Overrun struct {
+	// Address is the address of the message being handled.
+	Address string
+	Budget  time.Duration
+	Took    time.Duration
+	// Shed is the number of messages dropped while the handler was over
+	// budget, if shedding.
+	Shed uint64
+}
+
+// Budgeted wraps h with a latency budget, so one slow integration (such as a
+// handler making HTTP requests) can't back up the rest of a show. Calls taking
+// longer than budget are passed to onOverrun when they finish, or logged if
+// it's nil. If shed is true, messages arriving while a call has been running
+// for longer than the budget are dropped rather than queueing up behind it.
+// Arrival times are taken from the message's Meta if available, so with a
+// single worker the messages that queued up behind a slow call are shed once
+// it finishes.
+func Budgeted(h Handler, budget time.Duration, shed bool, onOverrun func(Overrun)) ContextHandler {
+	if onOverrun == nil {
+		onOverrun = func(o Overrun) {
+			log.Printf("Handler for %q took %v, over budget of %v (%d messages shed)", o.Address, o.Took, o.Budget, o.Shed)
+		}
+	}
+	return &budgeted{
+		h:         h,
+		budget:    budget,
+		shed:      shed,
+		onOverrun: onOverrun,
+		running:   make(map[uint64]time.Time),
+	}
+}
+
+type budgeted struct {
+	h         Handler
+	budget    time.Duration
+	shed      bool
+	onOverrun func(Overrun)
+
+	mu sync.Mutex
+	// running holds the start time of each call in progress, by an id.
+	running map[uint64]time.Time
+	nextID  uint64
+	// shedCount counts messages shed since the last Overrun.
+	shedCount uint64
+	// overFrom and overTo are when the latest finished call to go over
+	// budget did so and when it finished.
+	overFrom, overTo time.Time
+}
+
+func (b *budgeted) Handle(msg *osc.Message) error {
+	return b.HandleContext(context.Background(), msg)
+}
+
+func (b *budgeted) HandleContext(ctx context.Context, msg *osc.Message) error {
+	start := time.Now()
+	received := start
+	if meta, ok := MetaFromContext(ctx); ok && !meta.Received.IsZero() {
+		received = meta.Received
+	}
+	b.mu.Lock()
+	if b.shed && b.overrunning(received) {
+		b.shedCount++
+		b.mu.Unlock()
+		return nil
+	}
+	id := b.nextID
+	b.nextID++
+	b.running[id] = start
+	b.mu.Unlock()
+
+	err := handle(ctx, b.h, msg)
+
+	took := time.Since(start)
+	b.mu.Lock()
+	delete(b.running, id)
+	var shed uint64
+	if took > b.budget {
+		shed, b.shedCount = b.shedCount, 0
+		if end := start.Add(took); end.After(b.overTo) {
+			b.overFrom, b.overTo = start.Add(b.budget), end
+		}
+	}
+	b.mu.Unlock()
+	if took > b.budget {
+		b.onOverrun(Overrun{Address: msg.Pattern, Budget: b.budget, Took: took, Shed: shed})
+	}
+	return err
+}
+
+// overrunning reports whether a call had been running longer than the budget
+// at the time given, either one in progress or the latest to finish over
+// budget. b.mu must be held.
+func (b *budgeted) overrunning(at time.Time) bool {
+	if at.After(b.overFrom) && at.Before(b.overTo) {
+		return true
+	}
+	for _, start := range b.running {
+		if at.Sub(start) > b.budget {
+			return true
+		}
+	}
+	return false
+}
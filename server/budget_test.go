@@ -0,0 +1,98 @@
+package server
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pfcm/osc"
+)
+
+func TestBudgeted(t *testing.T) {
+	release := make(chan struct{})
+	var calls atomic.Int32
+	var overruns []Overrun
+	h := Budgeted(HandlerFunc(func(msg *osc.Message) error {
+		calls.Add(1)
+		if msg.Pattern == "/slow" {
+			<-release
+		}
+		return nil
+	}), 10*time.Millisecond, true, func(o Overrun) {
+		overruns = append(overruns, o)
+	})
+
+	if err := h.Handle(&osc.Message{Pattern: "/fast"}); err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		h.Handle(&osc.Message{Pattern: "/slow"})
+	}()
+	// Wait for the slow call to go over budget, then these are shed.
+	for calls.Load() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond)
+	for range 3 {
+		h.Handle(&osc.Message{Pattern: "/fast"})
+	}
+	if got := calls.Load(); got != 2 {
+		t.Errorf("handler called %d times while over budget, want: 2", got)
+	}
+
+	close(release)
+	wg.Wait()
+	// Recovered, so messages are handled again.
+	h.Handle(&osc.Message{Pattern: "/fast"})
+	if got := calls.Load(); got != 3 {
+		t.Errorf("handler called %d times after recovering, want: 3", got)
+	}
+	if len(overruns) != 1 {
+		t.Fatalf("got %d overruns, want: 1", len(overruns))
+	}
+	if o := overruns[0]; o.Address != "/slow" || o.Shed != 3 || o.Took < 20*time.Millisecond {
+		t.Errorf("overrun = %+v, want /slow with 3 shed", o)
+	}
+}
+
+func TestBudgetedOneWorker(t *testing.T) {
+	var calls int
+	var overruns []Overrun
+	h := Budgeted(HandlerFunc(func(msg *osc.Message) error {
+		calls++
+		if msg.Pattern == "/slow" {
+			time.Sleep(30 * time.Millisecond)
+		}
+		return nil
+	}), 10*time.Millisecond, true, func(o Overrun) {
+		overruns = append(overruns, o)
+	})
+	// One worker handles messages in turn, so ones received during the
+	// slow call are only handled after it.
+	at := func(received time.Time) context.Context {
+		return WithMeta(context.Background(), Meta{Received: received})
+	}
+	start := time.Now()
+	if err := h.HandleContext(at(start), &osc.Message{Pattern: "/slow"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range []time.Duration{5 * time.Millisecond, 20 * time.Millisecond, 25 * time.Millisecond} {
+		h.HandleContext(at(start.Add(d)), &osc.Message{Pattern: "/fast"})
+	}
+	// Within budget, then over it, so two are shed.
+	if calls != 2 {
+		t.Errorf("handler called %d times, want: 2", calls)
+	}
+	h.HandleContext(at(time.Now()), &osc.Message{Pattern: "/fast"})
+	if calls != 3 {
+		t.Errorf("handler called %d times after recovering, want: 3", calls)
+	}
+	if len(overruns) != 1 || overruns[0].Address != "/slow" {
+		t.Errorf("overruns = %+v, want one for /slow", overruns)
+	}
+}
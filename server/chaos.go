@@ -0,0 +1,79 @@
+package server
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/pfcm/osc"
+)
+
+// Chaos describes how Chaotic should misbehave when dispatching to a handler.
+// The zero Chaos is disabled, dispatching every message exactly once.
+type Chaos struct {
+	// Delay is the maximum random delay before each call.
+	Delay time.Duration
+	// Drop is the probability that a message is never passed to the handler.
+	Drop float64
+	// Duplicate is the probability that a message is passed to the handler
+	// twice.
+	Duplicate float64
+	// Seed seeds the random decisions, so a rehearsal can be repeated
+	// exactly.
+	Seed int64
+}
+
+// Chaotic wraps h so that dispatch is delayed, dropped or duplicated according
+// to c, to check show logic copes with a misbehaving network before going
+// live. Dropped messages aren't an error. A delay is cut short if the context
+// is done, returning its error.
+func Chaotic(h Handler, c Chaos) ContextHandler {
+	return &chaotic{h: h, c: c, rng: rand.New(rand.NewSource(c.Seed))}
+}
+
+type chaotic struct {
+	h Handler
+	c Chaos
+
+	mu  sync.Mutex
+	rng *rand.Rand
+}
+
+func (c *chaotic) Handle(msg *osc.Message) error {
+	return c.HandleContext(context.Background(), msg)
+}
+
+func (c *chaotic) HandleContext(ctx context.Context, msg *osc.Message) error {
+	c.mu.Lock()
+	if c.rng.Float64() < c.c.Drop {
+		c.mu.Unlock()
+		return nil
+	}
+	calls := 1
+	if c.rng.Float64() < c.c.Duplicate {
+		calls = 2
+	}
+	var delay time.Duration
+	if c.c.Delay > 0 {
+		delay = time.Duration(c.rng.Int63n(int64(c.c.Delay)))
+	}
+	c.mu.Unlock()
+
+	if delay > 0 {
+		t := time.NewTimer(delay)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		}
+	}
+	var err error
+	for range calls {
+		if e := handle(ctx, c.h, msg); e != nil {
+			err = e
+		}
+	}
+	return err
+}
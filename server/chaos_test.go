@@ -0,0 +1,60 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pfcm/osc"
+)
+
+func TestChaotic(t *testing.T) {
+	// run returns how many times each of n messages reached the handler.
+	run := func(c Chaos, n int) []int {
+		counts := make([]int, n)
+		h := Chaotic(HandlerFunc(func(msg *osc.Message) error {
+			counts[*msg.Arguments[0].(*osc.Int32)]++
+			return nil
+		}), c)
+		for i := range n {
+			if err := h.Handle(&osc.Message{Pattern: "/chaos", Arguments: []osc.Argument{osc.AsInt32(i)}}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return counts
+	}
+
+	for i, got := range run(Chaos{}, 100) {
+		if got != 1 {
+			t.Errorf("zero Chaos: message %d handled %d times, want: 1", i, got)
+		}
+	}
+
+	c := Chaos{Drop: 0.2, Duplicate: 0.2, Seed: 42}
+	first, second := run(c, 1000), run(c, 1000)
+	var dropped, duplicated int
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("message %d handled %d then %d times with the same seed", i, first[i], second[i])
+		}
+		switch first[i] {
+		case 0:
+			dropped++
+		case 2:
+			duplicated++
+		}
+	}
+	// Expect about 200 dropped and 160 duplicated.
+	if dropped < 100 || dropped > 300 || duplicated < 80 || duplicated > 240 {
+		t.Errorf("got %d dropped and %d duplicated of 1000, want about 200 and 160", dropped, duplicated)
+	}
+}
+
+func TestChaoticDelay(t *testing.T) {
+	h := Chaotic(HandlerFunc(func(*osc.Message) error { return nil }), Chaos{Delay: time.Hour, Seed: 1})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := h.HandleContext(ctx, &osc.Message{Pattern: "/slow"}); err != context.DeadlineExceeded {
+		t.Errorf("HandleContext with long delay = %v, want: %v", err, context.DeadlineExceeded)
+	}
+}
@@ -0,0 +1,177 @@
+package server
+
+import (
+	"fmt"
+	"hash/crc32"
+	"sync"
+	"time"
+
+	"github.com/pfcm/osc"
+)
+
+// Reassembler is a Handler that collects chunked transfers, as produced by
+// osc.Chunks, and passes each complete payload on. Chunks may arrive in any
+// order, and duplicates are ignored.
+//
+// At most maxTransfers transfers are in progress at once; past that the one
+// idle longest is dropped. Transfers idle for longer than transferTimeout are
+// dropped too.
+type Reassembler struct {
+	maxSize int
+	done    func(id int32, data []byte) error
+	timeout time.Duration
+
+	mu        sync.Mutex
+	transfers map[int32]*transfer
+}
+
+type transfer struct {
+	chunks map[int32][]byte
+	n      int // total bytes received so far
+	last   time.Time
+
+	// Set when the message finishing the transfer arrives.
+	finished bool
+	size     int
+	crc      uint32
+}
+
+const (
+	// maxTransfers limits how many transfers a Reassembler buffers at once.
+	maxTransfers = 64
+	// transferTimeout is how long a transfer may go without a chunk before
+	// it is dropped.
+	transferTimeout = 30 * time.Second
+)
+
+// NewReassembler returns a Reassembler that calls done with every completed
+// transfer. Transfers larger than maxSize bytes are rejected, as are
+// transfers whose chunks add up to more than that, counting overlaps.
+func NewReassembler(maxSize int, done func(id int32, data []byte) error) *Reassembler {
+	return &Reassembler{
+		maxSize:   maxSize,
+		done:      done,
+		timeout:   transferTimeout,
+		transfers: make(map[int32]*transfer),
+	}
+}
+
+func (r *Reassembler) Handle(msg *osc.Message) error {
+	var (
+		id   int32
+		data []byte
+		err  error
+	)
+	switch msg.TypeTag() {
+	case osc.ChunkTypeTag:
+		id, err = r.chunk(msg)
+	case osc.ChunkEndTypeTag:
+		id, err = r.end(msg)
+	default:
+		return fmt.Errorf("not part of a chunked transfer: %v", msg)
+	}
+	if err != nil {
+		return err
+	}
+	if data, err = r.complete(id); data == nil || err != nil {
+		return err
+	}
+	return r.done(id, data)
+}
+
+func (r *Reassembler) chunk(msg *osc.Message) (int32, error) {
+	id := int32(*msg.Arguments[0].(*osc.Int32))
+	off := int32(*msg.Arguments[1].(*osc.Int32))
+	data := *msg.Arguments[2].(*osc.Blob)
+	if off < 0 || int(off)+len(data) > r.maxSize {
+		return id, fmt.Errorf("transfer %d: chunk at %d (%d bytes) out of range", id, off, len(data))
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t := r.transfer(id)
+	if _, ok := t.chunks[off]; !ok {
+		if t.n+len(data) > r.maxSize {
+			delete(r.transfers, id)
+			return id, fmt.Errorf("transfer %d: more than %d bytes of chunks", id, r.maxSize)
+		}
+		t.chunks[off] = data
+		t.n += len(data)
+	}
+	return id, nil
+}
+
+func (r *Reassembler) end(msg *osc.Message) (int32, error) {
+	id := int32(*msg.Arguments[0].(*osc.Int32))
+	size := int(*msg.Arguments[1].(*osc.Int32))
+	if size < 0 || size > r.maxSize {
+		r.mu.Lock()
+		delete(r.transfers, id)
+		r.mu.Unlock()
+		return id, fmt.Errorf("transfer %d: size %d out of range", id, size)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t := r.transfer(id)
+	t.finished = true
+	t.size = size
+	t.crc = uint32(*msg.Arguments[2].(*osc.Int32))
+	return id, nil
+}
+
+// transfer returns the transfer with the given id, starting a new one if
+// necessary, and marks it active. r.mu must be held.
+func (r *Reassembler) transfer(id int32) *transfer {
+	now := time.Now()
+	t, ok := r.transfers[id]
+	if !ok {
+		r.expire(now)
+		t = &transfer{chunks: make(map[int32][]byte)}
+		r.transfers[id] = t
+	}
+	t.last = now
+	return t
+}
+
+// expire drops transfers that have been idle too long and, if there are
+// still maxTransfers in progress, the one idle longest. r.mu must be held.
+func (r *Reassembler) expire(now time.Time) {
+	oldest, found := int32(0), false
+	for id, t := range r.transfers {
+		if now.Sub(t.last) > r.timeout {
+			delete(r.transfers, id)
+			continue
+		}
+		if !found || t.last.Before(r.transfers[oldest].last) {
+			oldest, found = id, true
+		}
+	}
+	if len(r.transfers) >= maxTransfers {
+		delete(r.transfers, oldest)
+	}
+}
+
+// complete returns the payload of the transfer if all of it has arrived, or
+// nil if it is still in progress.
+func (r *Reassembler) complete(id int32) ([]byte, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t, ok := r.transfers[id]
+	if !ok || !t.finished || t.n < t.size {
+		return nil, nil
+	}
+	delete(r.transfers, id)
+
+	data := make([]byte, t.size)
+	for off, c := range t.chunks {
+		if int(off)+len(c) > t.size {
+			return nil, fmt.Errorf("transfer %d: chunk at %d (%d bytes) past the end (%d bytes)", id, off, len(c), t.size)
+		}
+		copy(data[off:], c)
+	}
+	if crc := crc32.ChecksumIEEE(data); crc != t.crc {
+		return nil, fmt.Errorf("transfer %d: checksum mismatch: %08x, want %08x", id, crc, t.crc)
+	}
+	return data, nil
+}
@@ -0,0 +1,126 @@
+package server
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/pfcm/osc"
+)
+
+func TestReassembler(t *testing.T) {
+	for _, c := range []struct {
+		size, chunk int
+	}{
+		{0, 10},
+		{1, 10},
+		{10, 10},
+		{11, 10},
+		{1000, 7},
+		{1 << 16, 1000},
+	} {
+		data := make([]byte, c.size)
+		rand.Read(data)
+		msgs := osc.Chunks("/chunk", 3, data, c.chunk)
+		// Shuffle and duplicate a few, to check the order doesn't matter.
+		msgs = append(msgs, msgs[:len(msgs)/2]...)
+		rand.Shuffle(len(msgs), func(i, j int) { msgs[i], msgs[j] = msgs[j], msgs[i] })
+
+		var got [][]byte
+		r := NewReassembler(1<<20, func(id int32, data []byte) error {
+			if id != 3 {
+				t.Errorf("transfer id = %d, want 3", id)
+			}
+			got = append(got, data)
+			return nil
+		})
+		for _, msg := range msgs {
+			// Encode and decode, so we're checking the wire format too.
+			parsed, err := osc.ParseMessage(msg.Append(nil))
+			if err != nil {
+				t.Fatalf("ParseMessage(%v): %v", msg, err)
+			}
+			if err := r.Handle(parsed); err != nil {
+				t.Fatalf("Handle(%v): %v", parsed, err)
+			}
+		}
+		if len(got) != 1 {
+			t.Fatalf("size %d, chunk %d: got %d transfers, want 1", c.size, c.chunk, len(got))
+		}
+		if !bytes.Equal(got[0], data) {
+			t.Errorf("size %d, chunk %d: reassembled data does not match", c.size, c.chunk)
+		}
+	}
+}
+
+func TestReassemblerErrors(t *testing.T) {
+	data := []byte("some data that is going to be corrupted")
+	msgs := osc.Chunks("/chunk", 1, data, 8)
+	*msgs[0].Arguments[2].(*osc.Blob) = osc.Blob("corrupt!")
+
+	r := NewReassembler(1<<10, func(int32, []byte) error {
+		t.Error("done called for corrupted transfer")
+		return nil
+	})
+	var err error
+	for _, msg := range msgs {
+		err = r.Handle(msg)
+	}
+	if err == nil {
+		t.Error("expected checksum error, got nil")
+	}
+
+	big := osc.Chunks("/chunk", 2, make([]byte, 2<<10), 1<<10)
+	if err := r.Handle(big[1]); err == nil {
+		t.Error("expected error for chunk past maximum size, got nil")
+	}
+	if err := r.Handle(&osc.Message{Pattern: "/chunk"}); err == nil {
+		t.Error("expected error for unrelated message, got nil")
+	}
+}
+
+func TestReassemblerLimits(t *testing.T) {
+	r := NewReassembler(16, func(int32, []byte) error { return nil })
+	chunk := func(id, off int32, data string) error {
+		b := osc.Blob(data)
+		return r.Handle(&osc.Message{Pattern: "/chunk", Arguments: []osc.Argument{osc.AsInt32(id), osc.AsInt32(off), &b}})
+	}
+	// Overlapping chunks count against the maximum size.
+	for off := range int32(8) {
+		if err := chunk(1, off, "12345678"); err != nil {
+			if off < 2 {
+				t.Errorf("chunk %d of 8 overlapping bytes: %v, want: nil", off, err)
+			}
+			break
+		}
+		if off == 7 {
+			t.Error("64 overlapping bytes with maxSize 16 accepted, want error")
+		}
+	}
+	if _, ok := r.transfers[1]; ok {
+		t.Error("transfer over the size limit still buffered")
+	}
+
+	for id := range int32(2 * maxTransfers) {
+		if err := chunk(id, 0, "x"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := len(r.transfers); n != maxTransfers {
+		t.Errorf("%d transfers started, %d buffered, want: %d", 2*maxTransfers, n, maxTransfers)
+	}
+	if _, ok := r.transfers[2*maxTransfers-1]; !ok {
+		t.Error("latest transfer dropped, want the oldest dropped")
+	}
+
+	for _, tr := range r.transfers {
+		tr.last = time.Now().Add(-2 * transferTimeout)
+	}
+	if err := chunk(-1, 0, "x"); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(r.transfers); n != 1 {
+		t.Errorf("after idle transfers expire, %d buffered, want: 1", n)
+	}
+}
@@ -0,0 +1,51 @@
+package server
+
+import "context"
+
+// Coercion is a set of conversions the HandleArgs handlers make between
+// argument types, because hardware is inconsistent about which numeric types
+// it sends, especially for toggles.
+type Coercion uint8
+
+const (
+	// IntBools decodes int32 arguments as bools, with any non-zero value
+	// true.
+	IntBools Coercion = 1 << iota
+	// FloatBools decodes float32 arguments as bools, with any non-zero
+	// value true.
+	FloatBools
+	// FloatInts decodes float32 arguments as int32s, truncating them.
+	FloatInts
+	// IntFloats decodes int32 arguments as float32s.
+	IntFloats
+	// BoolNumbers decodes True and False as 1 and 0, for int32s and
+	// float32s.
+	BoolNumbers
+
+	// Lenient makes every conversion.
+	Lenient = IntBools | FloatBools | FloatInts | IntFloats | BoolNumbers
+)
+
+// WithCoercion sets the conversions made by HandleArgs handlers for messages
+// received by the Listener. The default is none, so arguments must have
+// exactly the expected types.
+func WithCoercion(c Coercion) Option {
+	return func(l *Listener) {
+		l.coercion = c
+	}
+}
+
+type coercionKey struct{}
+
+// withCoercion returns a context carrying c, if it's not empty.
+func withCoercion(ctx context.Context, c Coercion) context.Context {
+	if c == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, coercionKey{}, c)
+}
+
+func coercionFromContext(ctx context.Context) Coercion {
+	c, _ := ctx.Value(coercionKey{}).(Coercion)
+	return c
+}
@@ -0,0 +1,106 @@
+package server
+
+// CompiledPattern is a Pattern compiled for matching many addresses. It
+// simulates the pattern as an NFA with bit-parallel sets of states, so a match
+// takes time proportional to the length of the address, times the number of
+// 64 bit words needed for the states, however many wildcards there are. It
+// doesn't allocate for patterns of up to 255 parts.
+type CompiledPattern struct {
+	pattern Pattern
+	// State i means the first i parts of the pattern have matched, so
+	// there are n+1 states, stored in words uint64s.
+	n, words int
+	// accept holds, for each byte, the states whose next part matches it,
+	// at accept[b*words:(b+1)*words]. "*"s aren't included.
+	accept []uint64
+	// star holds the states whose next part is a "*".
+	star []uint64
+}
+
+// Compile compiles the pattern for repeated matching.
+func (p Pattern) Compile() *CompiledPattern {
+	n := len(p.matchers)
+	words := (n + 64) / 64
+	c := &CompiledPattern{
+		pattern: p,
+		n:       n,
+		words:   words,
+		accept:  make([]uint64, 256*words),
+		star:    make([]uint64, words),
+	}
+	for i, m := range p.matchers {
+		if w, ok := m.(wildcard); ok && !w.single {
+			c.star[i/64] |= 1 << (i % 64)
+			continue
+		}
+		for b := range 256 {
+			if m.match(byte(b)) != noMatch {
+				c.accept[b*words+i/64] |= 1 << (i % 64)
+			}
+		}
+	}
+	return c
+}
+
+// CompilePattern parses and compiles a pattern.
+func CompilePattern(s string) (*CompiledPattern, error) {
+	p, err := ParsePattern(s)
+	if err != nil {
+		return nil, err
+	}
+	return p.Compile(), nil
+}
+
+// Match reports whether s matches the pattern, with the same results as
+// Pattern.Match.
+func (c *CompiledPattern) Match(s string) bool {
+	var buf [8]uint64
+	var cur, next []uint64
+	if 2*c.words <= len(buf) {
+		cur, next = buf[:c.words], buf[c.words:2*c.words]
+	} else {
+		cur, next = make([]uint64, c.words), make([]uint64, c.words)
+	}
+	cur[0] = 1
+	c.closure(cur)
+	for i := 0; i < len(s); i++ {
+		accept := c.accept[int(s[i])*c.words:][:c.words]
+		var carry, alive uint64
+		for w := range cur {
+			// Matching parts move on to the next state, "*"s
+			// stay where they are.
+			x := cur[w] & accept[w]
+			next[w] = x<<1 | carry | cur[w]&c.star[w]
+			carry = x >> 63
+			alive |= next[w]
+		}
+		if alive == 0 {
+			return false
+		}
+		c.closure(next)
+		cur, next = next, cur
+	}
+	return cur[c.n/64]&(1<<(c.n%64)) != 0
+}
+
+// closure adds the states reachable from set without reading any input, by
+// skipping "*"s.
+func (c *CompiledPattern) closure(set []uint64) {
+	for changed := true; changed; {
+		changed = false
+		var carry uint64
+		for w := range set {
+			x := set[w] & c.star[w]
+			add := x<<1 | carry
+			carry = x >> 63
+			if add&^set[w] != 0 {
+				set[w] |= add
+				changed = true
+			}
+		}
+	}
+}
+
+func (c *CompiledPattern) String() string {
+	return c.pattern.String()
+}
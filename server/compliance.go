@@ -0,0 +1,101 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/pfcm/osc"
+)
+
+// Compliance is the result of Node.Probe: which of the OSC 1.1 mandatory
+// argument types a peer handles, and which optional features it supports.
+type Compliance struct {
+	// Types holds, for each of osc.MandatoryTypeTags, whether the peer
+	// echoed an argument of that type back intact.
+	Types map[rune]bool
+	// Capabilities are the features the peer advertised when asked, as
+	// answered by HandleCapabilities, including the stream transports
+	// osc.CapSLIP and osc.CapTCP. It's nil if the peer didn't answer.
+	Capabilities osc.Capabilities
+}
+
+// HandleCapabilities registers a handler answering queries on
+// osc.CapabilitiesAddress with caps, in the convention of Replying, so peers
+// can find out which optional features and transports the Listener's program
+// supports, see Node.Probe.
+func (l *Listener) HandleCapabilities(caps osc.Capabilities) {
+	l.Handle(osc.CapabilitiesAddress, l.Replying(func(context.Context, *osc.Message) ([]osc.Argument, error) {
+		return caps.Message().Arguments, nil
+	}))
+}
+
+// Compliant reports whether the peer handled every mandatory type.
+func (c Compliance) Compliant() bool {
+	for _, t := range osc.MandatoryTypeTags {
+		if !c.Types[t] {
+			return false
+		}
+	}
+	return true
+}
+
+// probeTimeout is how long Probe waits for each echo.
+const probeTimeout = time.Second
+
+// probeArguments are sample arguments of each mandatory type.
+func probeArguments() []osc.Argument {
+	f := osc.Float32(0.25)
+	b := osc.Blob{1, 2, 3, 0, 0xc0}
+	return []osc.Argument{
+		osc.AsInt32(-7), &f, osc.AsString("probe"), &b,
+		&osc.TimeTag{Time: time.Unix(1700000000, 0)},
+		osc.True{}, osc.False{}, osc.Null{}, osc.Impulse{},
+	}
+}
+
+// Probe checks which of the OSC 1.1 mandatory argument types the host:port
+// addr supports, by sending one of each to LatencyAddress and checking it's
+// echoed back unchanged. The peer must echo messages there as
+// HandleDiagnostics does. A type is unsupported if its echo is wrong or
+// doesn't arrive within a second; if nothing is echoed at all Probe returns an
+// error. Probe also asks for the peer's capabilities, see HandleCapabilities.
+// The Node must be serving.
+func (n *Node) Probe(ctx context.Context, addr string) (Compliance, error) {
+	c := Compliance{Types: make(map[rune]bool)}
+	echoed := false
+	for _, arg := range probeArguments() {
+		callCtx, cancel := context.WithTimeout(ctx, probeTimeout)
+		reply, err := n.Call(callCtx, addr, LatencyAddress, arg)
+		cancel()
+		if ctx.Err() != nil {
+			return c, ctx.Err()
+		}
+		if err != nil {
+			if errors.Is(err, context.DeadlineExceeded) {
+				continue
+			}
+			return c, err
+		}
+		echoed = true
+		want := &osc.Message{Pattern: LatencyReplyAddress, Arguments: []osc.Argument{arg}}
+		got := &osc.Message{Pattern: reply.Pattern, Arguments: reply.Arguments[:min(len(reply.Arguments), 1)]}
+		c.Types[arg.TypeTag()] = len(osc.Diff(got, want)) == 0
+	}
+	if !echoed {
+		return c, fmt.Errorf("no echoes from %s, is it handling %s?", addr, LatencyAddress)
+	}
+
+	callCtx, cancel := context.WithTimeout(ctx, probeTimeout)
+	reply, err := n.Call(callCtx, addr, osc.CapabilitiesAddress)
+	cancel()
+	if ctx.Err() != nil {
+		return c, ctx.Err()
+	}
+	if err == nil {
+		// Unanswered or malformed replies leave Capabilities nil.
+		c.Capabilities, _ = osc.ParseCapabilities(&osc.Message{Pattern: osc.CapabilitiesAddress, Arguments: reply.Arguments})
+	}
+	return c, nil
+}
@@ -0,0 +1,47 @@
+package server
+
+import (
+	"context"
+	"maps"
+	"testing"
+
+	"github.com/pfcm/osc"
+)
+
+func TestProbe(t *testing.T) {
+	client, err := ListenNode("127.0.0.1:0", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	peer, err := ListenNode("127.0.0.1:0", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		client.Close()
+		peer.Close()
+	})
+	peer.HandleDiagnostics()
+	serve(t, client.Listener)
+	serve(t, peer.Listener)
+
+	c, err := client.Probe(context.Background(), peer.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !c.Compliant() {
+		t.Errorf("Probe(self) = %v, want compliant", c.Types)
+	}
+	if c.Capabilities != nil {
+		t.Errorf("Probe() without HandleCapabilities found capabilities %v, want: nil", c.Capabilities)
+	}
+
+	want := osc.Capabilities{osc.CapSLIP: true, osc.CapTCP: true}
+	peer.HandleCapabilities(want)
+	if c, err = client.Probe(context.Background(), peer.LocalAddr().String()); err != nil {
+		t.Fatal(err)
+	}
+	if !maps.Equal(c.Capabilities, want) {
+		t.Errorf("Probe() capabilities = %v, want: %v", c.Capabilities, want)
+	}
+}
@@ -0,0 +1,53 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/pfcm/osc"
+)
+
+// Addresses of the diagnostic handlers registered by HandleDiagnostics.
+const (
+	// TimeAddress is where peers ask for the Listener's clock. The reply
+	// is sent to TimeReplyAddress with the current time as a TimeTag.
+	TimeAddress      = "/time"
+	TimeReplyAddress = "/time/reply"
+	// LatencyAddress echoes messages back to LatencyReplyAddress, with the
+	// original arguments followed by the time the message was received and
+	// the time the reply was sent, as TimeTags. If the peer sends the time
+	// it sent the message as the first argument, it has all four
+	// timestamps needed to work out the round trip time and clock offset
+	// as NTP does.
+	LatencyAddress      = "/latency"
+	LatencyReplyAddress = "/latency/reply"
+)
+
+// HandleDiagnostics registers handlers on TimeAddress and LatencyAddress,
+// replying from the Listener's connection, so latency and clock offset can be
+// measured from any OSC client.
+func (l *Listener) HandleDiagnostics() {
+	l.Handle(TimeAddress, ContextHandlerFunc(func(ctx context.Context, msg *osc.Message) error {
+		meta, _ := MetaFromContext(ctx)
+		if meta.Addr == nil {
+			return errors.New("time request without a sender")
+		}
+		now := osc.TimeTag{Time: time.Now()}
+		return l.SendToContext(ctx, meta.Addr, &osc.Message{Pattern: TimeReplyAddress, Arguments: []osc.Argument{&now}})
+	}))
+	l.Handle(LatencyAddress, ContextHandlerFunc(func(ctx context.Context, msg *osc.Message) error {
+		meta, _ := MetaFromContext(ctx)
+		if meta.Addr == nil {
+			return errors.New("latency request without a sender")
+		}
+		received := osc.TimeTag{Time: meta.Received}
+		if received.IsZero() {
+			received.Time = time.Now()
+		}
+		sent := osc.TimeTag{Time: time.Now()}
+		args := append(slices.Clip(msg.Arguments), &received, &sent)
+		return l.SendToContext(ctx, meta.Addr, &osc.Message{Pattern: LatencyReplyAddress, Arguments: args})
+	}))
+}
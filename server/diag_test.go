@@ -0,0 +1,52 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pfcm/osc"
+)
+
+func TestDiagnostics(t *testing.T) {
+	conn := listen(t)
+	l := NewListener(conn, 1)
+	l.HandleDiagnostics()
+	serve(t, l)
+
+	peer := listen(t)
+	buf := make([]byte, 128)
+	read := func() *osc.Message {
+		t.Helper()
+		peer.SetReadDeadline(time.Now().Add(5 * time.Second))
+		n, _, err := peer.ReadFrom(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		msg, err := osc.ParseMessage(buf[:n])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return msg
+	}
+
+	before := time.Now()
+	send(t, peer, conn.LocalAddr(), TimeAddress)
+	msg := read()
+	if err := msg.CheckTypes("t"); msg.Pattern != TimeReplyAddress || err != nil {
+		t.Fatalf("time reply = %v, want: %s with a time tag", msg, TimeReplyAddress)
+	}
+	if got := msg.Arguments[0].(*osc.TimeTag).Time; got.Before(before.Add(-time.Second)) || got.After(time.Now().Add(time.Second)) {
+		t.Errorf("time reply = %v, want about %v", got, before)
+	}
+
+	t0 := osc.TimeTag{Time: time.Now()}
+	send(t, peer, conn.LocalAddr(), LatencyAddress, &t0, osc.AsInt32(7))
+	msg = read()
+	if err := msg.CheckTypes("titt"); msg.Pattern != LatencyReplyAddress || err != nil {
+		t.Fatalf("latency reply = %v, want: %s with the arguments and two time tags", msg, LatencyReplyAddress)
+	}
+	received, sent := msg.Arguments[2].(*osc.TimeTag).Time, msg.Arguments[3].(*osc.TimeTag).Time
+	if sent.Before(received) {
+		t.Errorf("latency reply sent at %v, before receiving at %v", sent, received)
+	}
+}
@@ -0,0 +1,45 @@
+package server
+
+import (
+	"context"
+	"sync"
+)
+
+// group is a collection of goroutines working on the same task, which are all
+// cancelled when one of them fails. It's a cut down golang.org/x/sync/errgroup,
+// so the module doesn't need the dependency.
+type group struct {
+	wg     sync.WaitGroup
+	cancel context.CancelFunc
+
+	once sync.Once
+	err  error
+}
+
+// newGroup returns a group and a context that is cancelled when any goroutine
+// in the group returns an error, or Wait returns.
+func newGroup(ctx context.Context) (*group, context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	return &group{cancel: cancel}, ctx
+}
+
+// Go runs f in a new goroutine.
+func (g *group) Go(f func() error) {
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		if err := f(); err != nil {
+			g.once.Do(func() {
+				g.err = err
+				g.cancel()
+			})
+		}
+	}()
+}
+
+// Wait waits for all of the goroutines to finish, returning the first error.
+func (g *group) Wait() error {
+	g.wg.Wait()
+	g.cancel()
+	return g.err
+}
@@ -0,0 +1,103 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Match reports whether address matches the pattern, with the same rules as
+// Pattern.Match. Unlike ParsePattern followed by Pattern.Match it doesn't
+// allocate, so it's better suited to matching a pattern only a few times.
+func Match(pattern, address string) (bool, error) {
+	if err := validate(pattern); err != nil {
+		return false, err
+	}
+	return match(pattern, address), nil
+}
+
+// validate checks a pattern is well formed, so match doesn't have to.
+func validate(p string) error {
+	for i := 0; i < len(p); i++ {
+		if p[i] != '[' {
+			continue
+		}
+		end := strings.IndexByte(p[i+1:], ']')
+		if end < 0 {
+			return fmt.Errorf("expect %q somewhere, got: %q", "]", p[i+1:])
+		}
+		class := p[i+1 : i+1+end]
+		class, _ = strings.CutPrefix(class, "!")
+		for j := 1; j+1 < len(class); j++ {
+			if lo, hi := class[j-1], class[j+1]; class[j] == '-' && hi < lo {
+				return fmt.Errorf("invalid range %c-%c, %c<%c", lo, hi, hi, lo)
+			}
+		}
+		i += end + 1
+	}
+	return nil
+}
+
+// match reports whether s matches the pattern p, which must be valid.
+func match(p, s string) bool {
+	// When we reach a *, we first try matching it against nothing. If
+	// that doesn't work out we come back and try it against one more
+	// byte, and so on.
+	var px, sx int
+	starPx, starSx := -1, -1
+	for px < len(p) || sx < len(s) {
+		if px < len(p) {
+			switch c := p[px]; c {
+			case '*':
+				starPx, starSx = px, sx+1
+				px++
+				continue
+			case '?':
+				if sx < len(s) {
+					px++
+					sx++
+					continue
+				}
+			case '[':
+				end := strings.IndexByte(p[px+1:], ']')
+				if sx < len(s) && classMatch(p[px+1:px+1+end], s[sx]) {
+					px += end + 2
+					sx++
+					continue
+				}
+			default:
+				if sx < len(s) && s[sx] == c {
+					px++
+					sx++
+					continue
+				}
+			}
+		}
+		if starPx >= 0 && starSx <= len(s) {
+			px, sx = starPx+1, starSx
+			starSx++
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+// classMatch reports whether c is in a character class, the part of a
+// pattern between "[" and "]".
+func classMatch(class string, c byte) bool {
+	class, invert := strings.CutPrefix(class, "!")
+	in := false
+	for i := 0; i < len(class); i++ {
+		// A - is a range, unless it's at either end.
+		if class[i] == '-' && i > 0 && i+1 < len(class) {
+			if class[i-1] <= c && c <= class[i+1] {
+				in = true
+			}
+			continue
+		}
+		if class[i] == c {
+			in = true
+		}
+	}
+	return in != invert
+}
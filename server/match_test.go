@@ -0,0 +1,246 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/pfcm/osc"
+)
+
+func TestMatch(t *testing.T) {
+	// Check Match agrees with Pattern.Match, on lots of random patterns.
+	const (
+		patternChars = "ab/*?[]!-"
+		inputChars   = "abc/-!"
+	)
+	str := func(chars string, n int) string {
+		b := make([]byte, rand.Intn(n))
+		for i := range b {
+			b[i] = chars[rand.Intn(len(chars))]
+		}
+		return string(b)
+	}
+	for i := 0; i < 10000; i++ {
+		p := str(patternChars, 10)
+		pattern, perr := ParsePattern(p)
+		for j := 0; j < 10; j++ {
+			s := str(inputChars, 8)
+			got, err := Match(p, s)
+			if (err != nil) != (perr != nil) {
+				t.Fatalf("Match(%q, %q) error = %v, ParsePattern error = %v", p, s, err, perr)
+			}
+			if err != nil {
+				break
+			}
+			if want := pattern.Match(s); got != want {
+				t.Errorf("Match(%q, %q) = %t, Pattern.Match = %t", p, s, got, want)
+			}
+		}
+	}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		Match("/a/*/[a-c]?/*x*y", "/a/b/c/d/e/xyz")
+	})
+	if allocs > 0 {
+		t.Errorf("Match allocated %.1f times", allocs)
+	}
+}
+
+func BenchmarkMatch(b *testing.B) {
+	const (
+		pattern = "/mixer/ch/[0-9]*/fader"
+		address = "/mixer/ch/12/fader"
+	)
+	b.Run("Match", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			Match(pattern, address)
+		}
+	})
+	b.Run("Pattern", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			p, _ := ParsePattern(pattern)
+			p.Match(address)
+		}
+	})
+	b.Run("Compiled", func(b *testing.B) {
+		c, _ := CompilePattern(pattern)
+		for i := 0; i < b.N; i++ {
+			c.Match(address)
+		}
+	})
+}
+
+func TestMatchDirection(t *testing.T) {
+	for _, c := range []struct {
+		direction MatchDirection
+		handler   string
+		msg       string
+		want      bool
+	}{
+		{MessagePattern, "/a/b", "/a/*", true},
+		{MessagePattern, "/a/*", "/a/b", false},
+		{MessagePattern, "/a/*", "/a/*", true},
+		{MessagePattern, "/a/b", "/a/[b", false},
+		{HandlerPattern, "/a/b", "/a/*", false},
+		{HandlerPattern, "/a/*", "/a/b", true},
+		{HandlerPattern, "/a/[b", "/a/[b", true},
+		{HandlerPattern, "/a/[b", "/a/b", false},
+		{EitherPattern, "/a/b", "/a/*", true},
+		{EitherPattern, "/a/*", "/a/b", true},
+		{EitherPattern, "/a/b", "/a/c", false},
+		{EitherPattern, "/a/??", "/a/[b", true},
+	} {
+		l := NewListener(nil, 1, WithMatchDirection(c.direction))
+		got := false
+		l.Handle(c.handler, HandlerFunc(func(*osc.Message) error {
+			got = true
+			return nil
+		}))
+		l.handle(context.Background(), packet{msg: &osc.Message{Pattern: c.msg}})
+		if got != c.want {
+			t.Errorf("direction %d: handler %q matched message %q: %t, want %t", c.direction, c.handler, c.msg, got, c.want)
+		}
+		if m := l.MatchesFor(c.msg); (len(m) == 1) != c.want {
+			t.Errorf("direction %d: MatchesFor(%q) with handler %q = %v, want match: %t", c.direction, c.msg, c.handler, m, c.want)
+		}
+	}
+}
+
+func TestMatchesFor(t *testing.T) {
+	l := NewListener(nil, 1)
+	for _, p := range []string{"/mixer/1/fader", "/mixer/2/fader", "/mixer/1/mute", "/transport"} {
+		l.Handle(p, HandlerFunc(func(*osc.Message) error { return nil }))
+	}
+	for _, c := range []struct {
+		address string
+		want    []string
+	}{
+		{"/mixer/*/fader", []string{"/mixer/1/fader", "/mixer/2/fader"}},
+		{"/mixer/1/*", []string{"/mixer/1/fader", "/mixer/1/mute"}},
+		{"/transport", []string{"/transport"}},
+		{"/mixer/3/fader", nil},
+		{"/mixer/[1", nil},
+	} {
+		var got []string
+		for _, h := range l.MatchesFor(c.address) {
+			got = append(got, h.Address)
+		}
+		if !slices.Equal(got, c.want) {
+			t.Errorf("MatchesFor(%q) = %v, want: %v", c.address, got, c.want)
+		}
+	}
+}
+
+func TestCompiledPattern(t *testing.T) {
+	// Check CompiledPattern.Match agrees with Pattern.Match, on lots of
+	// random patterns.
+	const (
+		patternChars = "ab/*?[]!-"
+		inputChars   = "abc/-!"
+	)
+	str := func(chars string, n int) string {
+		b := make([]byte, rand.Intn(n))
+		for i := range b {
+			b[i] = chars[rand.Intn(len(chars))]
+		}
+		return string(b)
+	}
+	for i := 0; i < 10000; i++ {
+		p := str(patternChars, 10)
+		pattern, err := ParsePattern(p)
+		if err != nil {
+			continue
+		}
+		c := pattern.Compile()
+		for j := 0; j < 10; j++ {
+			s := str(inputChars, 8)
+			if got, want := c.Match(s), pattern.Match(s); got != want {
+				t.Errorf("CompiledPattern(%q).Match(%q) = %t, Pattern.Match = %t", p, s, got, want)
+			}
+		}
+	}
+
+	// Long patterns need more than one word of states.
+	long := strings.Repeat("a*", 100)
+	c, err := CompilePattern(long)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{strings.Repeat("a", 100), strings.Repeat("ab", 100)} {
+		if !c.Match(s) {
+			t.Errorf("CompilePattern(%q).Match(%q) = false, want: true", long, s)
+		}
+	}
+	if c.Match(strings.Repeat("a", 99)) {
+		t.Errorf("CompilePattern(%q).Match(99 a's) = true, want: false", long)
+	}
+
+	c, err = CompilePattern("/a/*/[a-c]?/*x*y")
+	if err != nil {
+		t.Fatal(err)
+	}
+	allocs := testing.AllocsPerRun(100, func() {
+		c.Match("/a/b/c/d/e/xyz")
+	})
+	if allocs > 0 {
+		t.Errorf("CompiledPattern.Match allocated %.1f times", allocs)
+	}
+}
+
+func BenchmarkMatchPathological(b *testing.B) {
+	// Pattern.Match backtracks exponentially on this, so keep it short.
+	const pattern = "*a*a*a*a*b"
+	address := strings.Repeat("a", 16)
+	b.Run("Match", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			Match(pattern, address)
+		}
+	})
+	b.Run("Pattern", func(b *testing.B) {
+		p, _ := ParsePattern(pattern)
+		for i := 0; i < b.N; i++ {
+			p.Match(address)
+		}
+	})
+	b.Run("Compiled", func(b *testing.B) {
+		c, _ := CompilePattern(pattern)
+		for i := 0; i < b.N; i++ {
+			c.Match(address)
+		}
+	})
+}
+
+func TestMaxWildcards(t *testing.T) {
+	many := "/" + strings.Repeat("*", DefaultMaxWildcards+1)
+	for _, c := range []struct {
+		opts    []Option
+		handler string
+		msg     string
+		want    bool
+	}{
+		{nil, "/a/b", "/*/*", true},
+		{nil, "/a/b", many, false},
+		{[]Option{WithMaxWildcards(0)}, "/a/b", many, true},
+		{[]Option{WithMaxWildcards(1)}, "/a/b", "/*/*", false},
+		// Too complex patterns can still be matched by handler patterns.
+		{[]Option{WithMaxWildcards(1), WithMatchDirection(EitherPattern)}, "/*", "/*/*", true},
+	} {
+		l := NewListener(nil, 1, c.opts...)
+		got := false
+		l.Handle(c.handler, HandlerFunc(func(*osc.Message) error {
+			got = true
+			return nil
+		}))
+		err := l.handle(context.Background(), packet{msg: &osc.Message{Pattern: c.msg}})
+		if got != c.want {
+			t.Errorf("handler %q matched message %q with %d options: %t, want %t", c.handler, c.msg, len(c.opts), got, c.want)
+		}
+		if !got && len(c.opts) < 2 && !errors.Is(err, ErrPatternTooComplex) {
+			t.Errorf("handle(%q) with %d options = %v, want: %v", c.msg, len(c.opts), err, ErrPatternTooComplex)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/pfcm/osc"
+)
+
+// Addresses used for keeping NAT mappings open and finding peers behind NATs.
+const (
+	// KeepAliveAddress is the address of keepalive messages, which have no
+	// arguments and can be ignored.
+	KeepAliveAddress = "/keepalive"
+	// RendezvousAddress is where peers send a String naming a meeting to a
+	// Rendezvous server, to be told the address of the other peer at the
+	// same meeting.
+	RendezvousAddress = "/rendezvous"
+	// RendezvousPeerAddress is the reply from a Rendezvous server, with the
+	// meeting's name and the other peer's address, as seen by the server, as
+	// a "host:port" String.
+	RendezvousPeerAddress = "/rendezvous/peer"
+)
+
+// KeepAlive sends a keepalive message to each of the peers every interval until
+// the context is done, so NATs and firewalls between them keep the mapping
+// open while the link is quiet. Errors sending are logged.
+func (n *Node) KeepAlive(ctx context.Context, interval time.Duration, peers ...net.Addr) error {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	msg := &osc.Message{Pattern: KeepAliveAddress}
+	for {
+		for _, p := range peers {
+			if err := n.SendToContext(ctx, p, msg); err != nil {
+				log.Printf("Error sending keepalive to %v: %v", p, err)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// rendezvousRetry is how often Rendezvous asks the server again, in case a
+// packet was lost.
+const rendezvousRetry = time.Second
+
+// Rendezvous asks the Rendezvous server at relay for the address of the other
+// peer meeting under name, waiting until it arrives or the context is done.
+// Once both peers have each other's address they can send to each other
+// directly, even from behind NATs, as long as the NATs map the socket to the
+// same public address for every destination. The Node must be serving to
+// receive the reply. A keepalive is sent to the peer straight away, to open
+// our NAT to it.
+func (n *Node) Rendezvous(ctx context.Context, relay net.Addr, name string) (net.Addr, error) {
+	t := time.NewTicker(rendezvousRetry)
+	defer t.Stop()
+	req := &osc.Message{Pattern: RendezvousAddress, Arguments: []osc.Argument{osc.AsString(name)}}
+	for {
+		if err := n.SendToContext(ctx, relay, req); err != nil {
+			return nil, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-t.C:
+		case r := <-n.rendezvous:
+			if r.relay.String() != relay.String() || r.name != name {
+				// A late reply to an earlier call.
+				continue
+			}
+			return r.peer, n.SendToContext(ctx, r.peer, &osc.Message{Pattern: KeepAliveAddress})
+		}
+	}
+}
+
+// rendezvousReply is a reply from a Rendezvous server.
+type rendezvousReply struct {
+	relay net.Addr
+	name  string
+	peer  net.Addr
+}
+
+// handleRendezvousPeer handles replies from Rendezvous servers, passing them
+// to Rendezvous.
+func (n *Node) handleRendezvousPeer(ctx context.Context, msg *osc.Message) error {
+	if err := msg.CheckTypes("ss"); err != nil {
+		return err
+	}
+	peer, err := net.ResolveUDPAddr("udp", string(*msg.Arguments[1].(*osc.String)))
+	if err != nil {
+		return err
+	}
+	meta, _ := MetaFromContext(ctx)
+	select {
+	case n.rendezvous <- rendezvousReply{meta.Addr, string(*msg.Arguments[0].(*osc.String)), peer}:
+	default:
+		// Nobody is waiting.
+	}
+	return nil
+}
+
+// rendezvousTTL is how long a Rendezvous server remembers a meeting.
+const rendezvousTTL = time.Minute
+
+// maxMeetings limits how many meetings a Rendezvous server remembers. Requests
+// for new meetings past that are refused.
+const maxMeetings = 1024
+
+// Rendezvous is a server that introduces pairs of peers to each other, telling
+// each the public address the other's messages arrive from.
+type Rendezvous struct {
+	l *Listener
+
+	mu       sync.Mutex
+	meetings map[string]*meeting
+	// swept is when expired meetings were last removed.
+	swept time.Time
+}
+
+type meeting struct {
+	peers   []net.Addr
+	updated time.Time
+}
+
+// NewRendezvous returns a Rendezvous server handling requests received by l.
+func NewRendezvous(l *Listener) *Rendezvous {
+	r := &Rendezvous{l: l, meetings: make(map[string]*meeting)}
+	l.Handle(RendezvousAddress, ContextHandlerFunc(r.handle))
+	return r
+}
+
+func (r *Rendezvous) handle(ctx context.Context, msg *osc.Message) error {
+	if err := msg.CheckTypes("s"); err != nil {
+		return err
+	}
+	name := string(*msg.Arguments[0].(*osc.String))
+	meta, _ := MetaFromContext(ctx)
+	if meta.Addr == nil {
+		return fmt.Errorf("rendezvous request for %q without a sender", name)
+	}
+
+	r.mu.Lock()
+	now := time.Now()
+	if now.Sub(r.swept) > rendezvousTTL/4 {
+		r.expire(now)
+	}
+	m, ok := r.meetings[name]
+	if ok && now.Sub(m.updated) > rendezvousTTL {
+		// Expired, but not swept yet.
+		m.peers = nil
+	}
+	if !ok {
+		if len(r.meetings) >= maxMeetings {
+			r.mu.Unlock()
+			return fmt.Errorf("rendezvous request for %q refused: %d meetings in progress", name, maxMeetings)
+		}
+		m = &meeting{}
+		r.meetings[name] = m
+	}
+	m.updated = now
+	known := false
+	for _, p := range m.peers {
+		known = known || p.String() == meta.Addr.String()
+	}
+	if !known && len(m.peers) < 2 {
+		m.peers = append(m.peers, meta.Addr)
+	}
+	peers := m.peers
+	r.mu.Unlock()
+
+	if len(peers) < 2 {
+		return nil
+	}
+	// Answer both, the other peer may have given up on a lost reply.
+	for i, p := range peers {
+		other := peers[1-i]
+		reply := &osc.Message{
+			Pattern:   RendezvousPeerAddress,
+			Arguments: []osc.Argument{osc.AsString(name), osc.AsString(other.String())},
+		}
+		if err := r.l.SendToContext(ctx, p, reply); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// expire forgets meetings not updated for rendezvousTTL. r.mu must be held.
+func (r *Rendezvous) expire(now time.Time) {
+	r.swept = now
+	for n, m := range r.meetings {
+		if now.Sub(m.updated) > rendezvousTTL {
+			delete(r.meetings, n)
+		}
+	}
+}
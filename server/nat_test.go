@@ -0,0 +1,92 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/pfcm/osc"
+)
+
+func TestRendezvous(t *testing.T) {
+	relay := NewListener(listen(t), 1)
+	NewRendezvous(relay)
+	serve(t, relay)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var nodes [2]*Node
+	for i := range nodes {
+		n, err := ListenNode("127.0.0.1:0", 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { n.Close() })
+		go n.Serve(ctx)
+		nodes[i] = n
+	}
+	found := make(chan error, 2)
+	for i, n := range nodes {
+		want := nodes[1-i].LocalAddr().String()
+		go func() {
+			peer, err := n.Rendezvous(ctx, relay.conn.LocalAddr(), "studio")
+			if err == nil && peer.String() != want {
+				t.Errorf("Rendezvous() = %v, want: %v", peer, want)
+			}
+			found <- err
+		}()
+	}
+	for range nodes {
+		if err := <-found; err != nil {
+			t.Fatalf("Rendezvous(): %v", err)
+		}
+	}
+
+	// Keepalives should reach the peer.
+	go nodes[0].KeepAlive(ctx, 10*time.Millisecond, nodes[1].LocalAddr())
+	for {
+		if peers := nodes[1].Peers(); len(peers) > 0 && peers[len(peers)-1].Messages > 2 {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			t.Fatalf("keepalives didn't arrive, peers: %v", nodes[1].Peers())
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+func TestRendezvousLimits(t *testing.T) {
+	r := NewRendezvous(NewListener(listen(t), 1))
+	from := WithMeta(context.Background(), Meta{Addr: &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 9}})
+	request := func(name string) error {
+		return r.handle(from, &osc.Message{Pattern: RendezvousAddress, Arguments: []osc.Argument{osc.AsString(name)}})
+	}
+	for i := range maxMeetings {
+		if err := request(fmt.Sprint("meeting", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := request("one too many"); err == nil {
+		t.Error("request past maxMeetings = nil, want error")
+	}
+	if err := request("meeting0"); err != nil {
+		t.Errorf("request for an existing meeting when full = %v, want: nil", err)
+	}
+
+	// Once they expire there's room again.
+	r.mu.Lock()
+	for _, m := range r.meetings {
+		m.updated = time.Now().Add(-2 * rendezvousTTL)
+	}
+	r.swept = time.Time{}
+	r.mu.Unlock()
+	if err := request("one too many"); err != nil {
+		t.Errorf("request after meetings expired = %v, want: nil", err)
+	}
+	if n := len(r.meetings); n != 1 {
+		t.Errorf("%d meetings after expiry, want: 1", n)
+	}
+}
@@ -0,0 +1,46 @@
+package server
+
+import (
+	"net"
+
+	"github.com/pfcm/osc"
+)
+
+// Node is a client and a server on a single UDP socket, as many OSC devices
+// expect: messages are received on a fixed port and replies and commands are
+// sent from it. Handlers, serving, peers and shutting down are all the
+// embedded Listener's.
+type Node struct {
+	*Listener
+
+	// rendezvous passes replies from Rendezvous servers to Rendezvous.
+	rendezvous chan rendezvousReply
+	// calls holds the replies Call is waiting for.
+	calls *calls
+}
+
+// ListenNode opens a UDP socket on addr and returns a Node using it, with a
+// Listener configured by workers and opts.
+func ListenNode(addr string, workers int, opts ...Option) (*Node, error) {
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	n := &Node{
+		Listener:   NewListener(conn, workers, opts...),
+		rendezvous: make(chan rendezvousReply),
+		calls:      new(calls),
+	}
+	n.intercept = n.calls.deliver
+	n.Handle(RendezvousPeerAddress, ContextHandlerFunc(n.handleRendezvousPeer))
+	return n, nil
+}
+
+// Send sends a message to the host:port addr from the Node's socket.
+func (n *Node) Send(addr, pattern string, args ...osc.Argument) error {
+	to, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return err
+	}
+	return n.SendTo(to, &osc.Message{Pattern: pattern, Arguments: args})
+}
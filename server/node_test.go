@@ -0,0 +1,54 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pfcm/osc"
+)
+
+func TestNode(t *testing.T) {
+	a, err := ListenNode("127.0.0.1:0", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ListenNode("127.0.0.1:0", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pongs := make(chan string, 1)
+	a.Handle("/pong", ContextHandlerFunc(func(ctx context.Context, msg *osc.Message) error {
+		meta, _ := MetaFromContext(ctx)
+		pongs <- meta.Addr.String()
+		return nil
+	}))
+	b.Handle("/ping", ContextHandlerFunc(func(ctx context.Context, msg *osc.Message) error {
+		meta, _ := MetaFromContext(ctx)
+		return b.SendTo(meta.Addr, &osc.Message{Pattern: "/pong"})
+	}))
+	ctx := context.Background()
+	go a.Serve(ctx)
+	go b.Serve(ctx)
+
+	if err := a.Send(b.LocalAddr().String(), "/ping"); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case from := <-pongs:
+		if from != b.LocalAddr().String() {
+			t.Errorf("pong from %v, want: %v", from, b.LocalAddr())
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for pong")
+	}
+	if peers := b.Peers(); len(peers) != 1 || peers[0].Addr.String() != a.LocalAddr().String() {
+		t.Errorf("b.Peers() = %v, want just %v", peers, a.LocalAddr())
+	}
+
+	for _, n := range []*Node{a, b} {
+		if err := n.Shutdown(ctx); err != nil {
+			t.Errorf("Shutdown(): %v", err)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package server
+
+import "strings"
+
+// Normalization is a set of changes made to addresses before matching,
+// because devices disagree about details such as case, so handlers would
+// otherwise silently miss their messages.
+type Normalization uint8
+
+const (
+	// LowerCase folds ASCII letters to lower case.
+	LowerCase Normalization = 1 << iota
+	// CollapseSlashes replaces runs of "/" with a single one. Note this
+	// makes OSC 1.1's "//" wildcard a plain "/".
+	CollapseSlashes
+	// TrimTrailingSlash removes a "/" from the end of an address, unless
+	// it's the whole address.
+	TrimTrailingSlash
+)
+
+// WithNormalization normalizes the addresses of received messages, and of
+// handlers as they're registered, so they match despite the differences n
+// covers. Handlers see the normalized address in the message, mirrored
+// messages are sent as they were received.
+func WithNormalization(n Normalization) Option {
+	return func(l *Listener) {
+		l.normalization = n
+	}
+}
+
+// normalize applies n to address. It doesn't allocate if address is already
+// normalized.
+func (n Normalization) normalize(address string) string {
+	if n&LowerCase != 0 && strings.ContainsFunc(address, isUpper) {
+		address = strings.ToLower(address)
+	}
+	if n&CollapseSlashes != 0 {
+		for strings.Contains(address, "//") {
+			address = strings.ReplaceAll(address, "//", "/")
+		}
+	}
+	if n&TrimTrailingSlash != 0 && len(address) > 1 {
+		address = strings.TrimSuffix(address, "/")
+	}
+	return address
+}
+
+func isUpper(r rune) bool {
+	return 'A' <= r && r <= 'Z'
+}
@@ -0,0 +1,43 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pfcm/osc"
+)
+
+func TestNormalize(t *testing.T) {
+	for _, c := range []struct {
+		n    Normalization
+		in   string
+		want string
+	}{
+		{0, "/Mixer//Fader/", "/Mixer//Fader/"},
+		{LowerCase, "/Mixer/CH/1", "/mixer/ch/1"},
+		{CollapseSlashes, "//mixer///fader", "/mixer/fader"},
+		{TrimTrailingSlash, "/mixer/", "/mixer"},
+		{TrimTrailingSlash, "/", "/"},
+		{LowerCase | CollapseSlashes | TrimTrailingSlash, "/Mixer//Fader//", "/mixer/fader"},
+	} {
+		if got := c.n.normalize(c.in); got != c.want {
+			t.Errorf("Normalization(%b).normalize(%q) = %q, want: %q", c.n, c.in, got, c.want)
+		}
+	}
+	if allocs := testing.AllocsPerRun(100, func() {
+		(LowerCase | CollapseSlashes | TrimTrailingSlash).normalize("/mixer/ch/1/fader")
+	}); allocs != 0 {
+		t.Errorf("normalizing a normal address allocated %.1f times, want: 0", allocs)
+	}
+
+	l := NewListener(nil, 1, WithNormalization(LowerCase|TrimTrailingSlash))
+	var got string
+	l.Handle("/Mixer/fader", HandlerFunc(func(msg *osc.Message) error {
+		got = msg.Pattern
+		return nil
+	}))
+	l.handle(context.Background(), packet{msg: &osc.Message{Pattern: "/MIXER/Fader/"}})
+	if got != "/mixer/fader" {
+		t.Errorf("handler got %q, want: %q", got, "/mixer/fader")
+	}
+}
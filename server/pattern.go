@@ -26,21 +26,37 @@ func ParsePattern(s string) (Pattern, error) {
 	return p, nil
 }
 
+// ErrPatternTooComplex is returned when matching a pattern would take too much
+// work.
+var ErrPatternTooComplex = errors.New("pattern too complex")
+
 // Match tries to match the provided string against the receiver
-// pattern.
+// pattern. It backtracks, which can take time exponential in the number of
+// wildcards, so patterns from untrusted senders should be matched with
+// MatchLimit or a CompiledPattern instead.
 func (p Pattern) Match(s string) bool {
+	ok, _ := p.MatchLimit(s, 0)
+	return ok
+}
+
+// MatchLimit is like Match, but gives up with ErrPatternTooComplex after
+// trying steps states. If steps <= 0 there's no limit.
+func (p Pattern) MatchLimit(s string, steps int) (bool, error) {
 	states := []*matchState{{p.matchers, s}}
-	for len(states) > 0 {
+	for n := 0; len(states) > 0; n++ {
+		if steps > 0 && n >= steps {
+			return false, fmt.Errorf("%w: more than %d steps", ErrPatternTooComplex, steps)
+		}
 		var s *matchState
 		l := len(states) - 1
 		s, states = states[l], states[:l]
 		next, accept := s.match()
 		if accept {
-			return true
+			return true, nil
 		}
 		states = append(states, next...)
 	}
-	return false
+	return false, nil
 }
 
 func (p Pattern) String() string {
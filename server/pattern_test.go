@@ -1,8 +1,10 @@
 package server
 
 import (
+	"errors"
 	"fmt"
 	"math/rand"
+	"strings"
 	"testing"
 )
 
@@ -292,3 +294,17 @@ func TestParseCharClass(t *testing.T) {
 		})
 	}
 }
+
+func TestPatternMatchLimit(t *testing.T) {
+	p, err := ParsePattern("*a*a*a*a*a*a*a*a*b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := strings.Repeat("a", 64)
+	if _, err := p.MatchLimit(address, 10000); !errors.Is(err, ErrPatternTooComplex) {
+		t.Errorf("MatchLimit(pathological) = %v, want: %v", err, ErrPatternTooComplex)
+	}
+	if ok, err := p.MatchLimit(address+"b", 10000); !ok || err != nil {
+		t.Errorf("MatchLimit(match) = %t, %v, want: true, nil", ok, err)
+	}
+}
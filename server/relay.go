@@ -0,0 +1,227 @@
+package server
+
+import (
+	"context"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/pfcm/osc"
+)
+
+// Addresses of messages between peers and a Relay.
+const (
+	// RelayJoinAddress is where peers send the name of a room to join, as a
+	// String, optionally followed by a String token for the Relay's Auth.
+	RelayJoinAddress = "/relay/join"
+	// RelayLeaveAddress is where peers send a message with no arguments to
+	// leave their room.
+	RelayLeaveAddress = "/relay/leave"
+	// RelayJoinedAddress is the reply to a successful join, with the room's
+	// name.
+	RelayJoinedAddress = "/relay/joined"
+	// RelayDeniedAddress is the reply when Auth refuses a join, with the
+	// room's name.
+	RelayDeniedAddress = "/relay/denied"
+)
+
+// relayTTL is how long a peer stays in a room without sending anything.
+// Peers should send keepalives more often than this.
+const relayTTL = 2 * time.Minute
+
+// Limits on the peers and rooms of a Relay. Joins past them are denied.
+const (
+	maxRelayPeers = 1024
+	maxRelayRooms = 256
+)
+
+// Relay forwards packets between peers that can't reach each other directly,
+// for example over the internet for networked music. Peers join a room, and
+// every packet they send to the relay other than the relay's own messages is
+// forwarded unchanged to the rest of the room. A peer is in at most one room
+// at a time, and leaves it by sending to RelayLeaveAddress or by being silent
+// for a couple of minutes. Joins are denied once the relay has maxRelayPeers
+// peers or, for a new room, maxRelayRooms rooms.
+type Relay struct {
+	conn net.PacketConn
+
+	// Auth, if set, decides whether a peer may join a room, given the
+	// token it sent, which is empty if there wasn't one.
+	Auth func(addr net.Addr, room, token string) bool
+
+	mu    sync.Mutex
+	peers map[string]*relayPeer
+	rooms map[string]map[string]*relayPeer
+	// swept is when expired peers were last removed from every room.
+	swept time.Time
+}
+
+type relayPeer struct {
+	addr     net.Addr
+	room     string
+	lastSeen time.Time
+}
+
+// NewRelay returns a Relay using conn.
+func NewRelay(conn net.PacketConn) *Relay {
+	return &Relay{
+		conn:  conn,
+		peers: make(map[string]*relayPeer),
+		rooms: make(map[string]map[string]*relayPeer),
+	}
+}
+
+// Serve relays packets until the context is cancelled or there's an error
+// reading from the connection.
+func (r *Relay) Serve(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		<-ctx.Done()
+		r.conn.SetReadDeadline(time.Now())
+	}()
+	buf := make([]byte, 1<<16) // ~max UDP packet size.
+	for {
+		n, addr, err := r.conn.ReadFrom(buf)
+		if n > 0 {
+			r.packet(addr, buf[:n])
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+	}
+}
+
+// Rooms returns the number of peers in each room.
+func (r *Relay) Rooms() map[string]int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	rooms := make(map[string]int, len(r.rooms))
+	for name, members := range r.rooms {
+		rooms[name] = len(members)
+	}
+	return rooms
+}
+
+// packet handles a single packet from addr.
+func (r *Relay) packet(addr net.Addr, b []byte) {
+	// Bundles and anything else that isn't a message is just forwarded.
+	if msg, err := osc.ParseMessage(b); err == nil {
+		switch msg.Pattern {
+		case RelayJoinAddress:
+			r.join(addr, msg)
+			return
+		case RelayLeaveAddress:
+			r.mu.Lock()
+			r.remove(addr.String())
+			r.mu.Unlock()
+			return
+		}
+	}
+
+	now := time.Now()
+	r.mu.Lock()
+	p, ok := r.peers[addr.String()]
+	if !ok {
+		r.mu.Unlock()
+		return
+	}
+	p.lastSeen = now
+	if now.Sub(r.swept) > relayTTL/4 {
+		r.expire(now)
+	}
+	var to []net.Addr
+	for key, q := range r.rooms[p.room] {
+		switch {
+		case q == p:
+		case now.Sub(q.lastSeen) > relayTTL:
+			r.remove(key)
+		default:
+			to = append(to, q.addr)
+		}
+	}
+	r.mu.Unlock()
+
+	for _, a := range to {
+		if _, err := r.conn.WriteTo(b, a); err != nil {
+			log.Printf("Error relaying to %v: %v", a, err)
+		}
+	}
+}
+
+// join handles a request to join a room.
+func (r *Relay) join(addr net.Addr, msg *osc.Message) {
+	var room, token string
+	switch {
+	case msg.CheckTypes("s") == nil:
+	case msg.CheckTypes("ss") == nil:
+		token = string(*msg.Arguments[1].(*osc.String))
+	default:
+		log.Printf("Invalid relay join from %v: %v", addr, msg)
+		return
+	}
+	room = string(*msg.Arguments[0].(*osc.String))
+	reply := RelayJoinedAddress
+	if r.Auth != nil && !r.Auth(addr, room, token) {
+		reply = RelayDeniedAddress
+	} else if !r.add(addr, room) {
+		log.Printf("Relay full, denying %v joining %q", addr, room)
+		reply = RelayDeniedAddress
+	}
+	b := osc.Message{Pattern: reply, Arguments: []osc.Argument{osc.AsString(room)}}.Append(nil)
+	if _, err := r.conn.WriteTo(b, addr); err != nil {
+		log.Printf("Error replying to %v: %v", addr, err)
+	}
+}
+
+// add puts the peer at addr in room, taking it out of any other, unless the
+// relay is full.
+func (r *Relay) add(addr net.Addr, room string) bool {
+	now := time.Now()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.expire(now)
+	key := addr.String()
+	if _, ok := r.peers[key]; !ok && len(r.peers) >= maxRelayPeers {
+		return false
+	}
+	if r.rooms[room] == nil && len(r.rooms) >= maxRelayRooms {
+		return false
+	}
+	r.remove(key)
+	p := &relayPeer{addr: addr, room: room, lastSeen: now}
+	r.peers[key] = p
+	if r.rooms[room] == nil {
+		r.rooms[room] = make(map[string]*relayPeer)
+	}
+	r.rooms[room][key] = p
+	return true
+}
+
+// expire removes peers that have been silent for longer than relayTTL from
+// every room. r.mu must be held.
+func (r *Relay) expire(now time.Time) {
+	r.swept = now
+	for key, p := range r.peers {
+		if now.Sub(p.lastSeen) > relayTTL {
+			r.remove(key)
+		}
+	}
+}
+
+// remove takes a peer out of its room. r.mu must be held.
+func (r *Relay) remove(key string) {
+	p, ok := r.peers[key]
+	if !ok {
+		return
+	}
+	delete(r.peers, key)
+	delete(r.rooms[p.room], key)
+	if len(r.rooms[p.room]) == 0 {
+		delete(r.rooms, p.room)
+	}
+}
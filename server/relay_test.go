@@ -0,0 +1,114 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/pfcm/osc"
+)
+
+func TestRelay(t *testing.T) {
+	conn := listen(t)
+	r := NewRelay(conn)
+	r.Auth = func(_ net.Addr, room, token string) bool {
+		return room != "private" || token == "secret"
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go r.Serve(ctx)
+
+	// expect reads the next message on c, checking its address.
+	expect := func(c net.PacketConn, address string) {
+		t.Helper()
+		c.SetReadDeadline(time.Now().Add(5 * time.Second))
+		buf := make([]byte, 1024)
+		n, _, err := c.ReadFrom(buf)
+		if err != nil {
+			t.Fatalf("waiting for %s: %v", address, err)
+		}
+		msg, err := osc.ParseMessage(buf[:n])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if msg.Pattern != address {
+			t.Fatalf("got %v, want a message to %s", msg, address)
+		}
+	}
+	a, b, c, d := listen(t), listen(t), listen(t), listen(t)
+	for _, j := range []struct {
+		conn  net.PacketConn
+		args  []osc.Argument
+		reply string
+	}{
+		{a, []osc.Argument{osc.AsString("private"), osc.AsString("secret")}, RelayJoinedAddress},
+		{b, []osc.Argument{osc.AsString("private"), osc.AsString("secret")}, RelayJoinedAddress},
+		{c, []osc.Argument{osc.AsString("private"), osc.AsString("guess")}, RelayDeniedAddress},
+		{d, []osc.Argument{osc.AsString("public")}, RelayJoinedAddress},
+	} {
+		send(t, j.conn, conn.LocalAddr(), RelayJoinAddress, j.args...)
+		expect(j.conn, j.reply)
+	}
+	if got, want := r.Rooms(), map[string]int{"private": 2, "public": 1}; !maps.Equal(got, want) {
+		t.Errorf("Rooms() = %v, want: %v", got, want)
+	}
+
+	send(t, a, conn.LocalAddr(), "/hello")
+	expect(b, "/hello")
+	send(t, b, conn.LocalAddr(), "/hello/back")
+	expect(a, "/hello/back")
+	// Nobody else should have been sent anything.
+	for _, other := range []net.PacketConn{c, d} {
+		other.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+		if _, _, err := other.ReadFrom(make([]byte, 1024)); err == nil {
+			t.Errorf("%v received a packet from another room", other.LocalAddr())
+		}
+	}
+
+	send(t, b, conn.LocalAddr(), RelayLeaveAddress)
+	want := map[string]int{"private": 1, "public": 1}
+	for deadline := time.Now().Add(5 * time.Second); !maps.Equal(r.Rooms(), want); {
+		if time.Now().After(deadline) {
+			t.Fatalf("Rooms() after leaving = %v, want: %v", r.Rooms(), want)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRelayLimits(t *testing.T) {
+	r := NewRelay(listen(t))
+	join := func(port int, room string) {
+		addr := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: port}
+		r.packet(addr, osc.Message{Pattern: RelayJoinAddress, Arguments: []osc.Argument{osc.AsString(room)}}.Append(nil))
+	}
+	for i := range maxRelayRooms + 1 {
+		join(20000+i, fmt.Sprint("room", i))
+	}
+	if got := len(r.Rooms()); got != maxRelayRooms {
+		t.Errorf("%d rooms joined, got %d, want: %d", maxRelayRooms+1, got, maxRelayRooms)
+	}
+	for i := range maxRelayPeers {
+		join(30000+i, "room0")
+	}
+	if got := len(r.peers); got != maxRelayPeers {
+		t.Errorf("%d peers joined, got %d, want: %d", maxRelayRooms+maxRelayPeers, got, maxRelayPeers)
+	}
+
+	// Silent peers are removed from every room, not just the sender's.
+	addr := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 20001}
+	r.mu.Lock()
+	for key, p := range r.peers {
+		if key != addr.String() {
+			p.lastSeen = time.Now().Add(-2 * relayTTL)
+		}
+	}
+	r.swept = time.Time{}
+	r.mu.Unlock()
+	r.packet(addr, osc.Message{Pattern: "/hello"}.Append(nil))
+	if got, want := r.Rooms(), map[string]int{"room1": 1}; !maps.Equal(got, want) {
+		t.Errorf("Rooms() after the rest went silent = %v, want: %v", got, want)
+	}
+}
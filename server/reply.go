@@ -0,0 +1,162 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/pfcm/osc"
+)
+
+// Prefixes of the addresses of replies, in the common convention where a
+// request to "/foo" is answered on "/reply/foo", or "/error/foo" with a
+// String describing the error if it failed.
+const (
+	ReplyPrefix = "/reply"
+	ErrorPrefix = "/error"
+)
+
+// Replying returns a handler calling f and sending its result back to the
+// sender from the Listener's connection: the arguments to ReplyPrefix followed
+// by the message's address, or the error's text to ErrorPrefix followed by the
+// address.
+func (l *Listener) Replying(f func(context.Context, *osc.Message) ([]osc.Argument, error)) ContextHandler {
+	return ContextHandlerFunc(func(ctx context.Context, msg *osc.Message) error {
+		meta, _ := MetaFromContext(ctx)
+		if meta.Addr == nil {
+			return fmt.Errorf("request to %q without a sender", msg.Pattern)
+		}
+		args, err := f(ctx, msg)
+		if err != nil {
+			return l.SendToContext(ctx, meta.Addr, &osc.Message{
+				Pattern:   ErrorPrefix + msg.Pattern,
+				Arguments: []osc.Argument{osc.AsString(err.Error())},
+			})
+		}
+		return l.SendToContext(ctx, meta.Addr, &osc.Message{Pattern: ReplyPrefix + msg.Pattern, Arguments: args})
+	})
+}
+
+// RemoteError is an error reply received by Node.Call.
+type RemoteError struct {
+	// Address is the address of the request.
+	Address string
+	// Message is the text of the error, if the reply had one.
+	Message string
+}
+
+func (e *RemoteError) Error() string {
+	return fmt.Sprintf("error from %s: %s", e.Address, e.Message)
+}
+
+// Call sends a message to the host:port addr and waits for the reply from it,
+// in the convention handled by Listener.Replying, until the context is done.
+// An error reply is returned as a *RemoteError. The Node must be serving to
+// receive the reply, which isn't passed to any handlers.
+func (n *Node) Call(ctx context.Context, addr, pattern string, args ...osc.Argument) (*osc.Message, error) {
+	to, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	key := callKey{to.String(), pattern}
+	reply := make(chan *osc.Message, 1)
+	n.calls.add(key, reply)
+	defer n.calls.remove(key, reply)
+	if err := n.SendToContext(ctx, to, &osc.Message{Pattern: pattern, Arguments: args}); err != nil {
+		return nil, err
+	}
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case msg := <-reply:
+		if strings.HasPrefix(msg.Pattern, ErrorPrefix) {
+			e := &RemoteError{Address: pattern}
+			if len(msg.Arguments) > 0 {
+				e.Message = fmt.Sprint(msg.Arguments[0])
+				if s, ok := msg.Arguments[0].(*osc.String); ok {
+					e.Message = string(*s)
+				}
+			}
+			return msg, e
+		}
+		return msg, nil
+	}
+}
+
+// callKey identifies the calls a reply is for: the peer's address and the
+// address of the request.
+type callKey struct {
+	peer, address string
+}
+
+// calls holds the replies Node.Call is waiting for.
+type calls struct {
+	mu      sync.Mutex
+	waiting map[callKey][]chan *osc.Message
+}
+
+func (c *calls) add(key callKey, reply chan *osc.Message) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.waiting == nil {
+		c.waiting = make(map[callKey][]chan *osc.Message)
+	}
+	c.waiting[key] = append(c.waiting[key], reply)
+}
+
+func (c *calls) remove(key callKey, reply chan *osc.Message) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	waiting := c.waiting[key]
+	for i, w := range waiting {
+		if w == reply {
+			waiting = append(waiting[:i], waiting[i+1:]...)
+			break
+		}
+	}
+	if len(waiting) == 0 {
+		delete(c.waiting, key)
+	} else {
+		c.waiting[key] = waiting
+	}
+}
+
+// deliver passes a reply to the oldest call waiting for it, reporting whether
+// there was one.
+func (c *calls) deliver(msg *osc.Message, meta Meta) bool {
+	if meta.Addr == nil {
+		return false
+	}
+	address, ok := strings.CutPrefix(msg.Pattern, ReplyPrefix)
+	if !ok {
+		address, ok = strings.CutPrefix(msg.Pattern, ErrorPrefix)
+	}
+	if msg.Pattern == LatencyReplyAddress {
+		// The diagnostic echo has its own reply address.
+		address, ok = LatencyAddress, true
+	}
+	if !ok {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	waiting := c.waiting[callKey{meta.Addr.String(), address}]
+	if len(waiting) == 0 {
+		return false
+	}
+	// The message may be reused once handling it is done, so pass on a
+	// copy.
+	cp, err := osc.ParseMessage(msg.Append(nil))
+	if err != nil {
+		return false
+	}
+	select {
+	case waiting[0] <- cp:
+	default:
+		return false
+	}
+	c.waiting[callKey{meta.Addr.String(), address}] = waiting[1:]
+	return true
+}
@@ -0,0 +1,60 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pfcm/osc"
+)
+
+func TestCall(t *testing.T) {
+	client, err := ListenNode("127.0.0.1:0", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv, err := ListenNode("127.0.0.1:0", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		client.Close()
+		srv.Close()
+	})
+	srv.Handle("/double", srv.Replying(func(_ context.Context, msg *osc.Message) ([]osc.Argument, error) {
+		if err := msg.CheckTypes("i"); err != nil {
+			return nil, err
+		}
+		return []osc.Argument{osc.AsInt32(2 * *msg.Arguments[0].(*osc.Int32))}, nil
+	}))
+	// The reply shouldn't reach handlers.
+	client.Handle("/reply/double", HandlerFunc(func(*osc.Message) error {
+		t.Error("reply passed to handler")
+		return nil
+	}))
+	serve(t, client.Listener)
+	serve(t, srv.Listener)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	reply, err := client.Call(ctx, srv.LocalAddr().String(), "/double", osc.AsInt32(21))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply.Pattern != "/reply/double" || reply.CheckTypes("i") != nil || *reply.Arguments[0].(*osc.Int32) != 42 {
+		t.Errorf("Call(/double 21) = %v, want: /reply/double 42", reply)
+	}
+
+	_, err = client.Call(ctx, srv.LocalAddr().String(), "/double", osc.AsString("x"))
+	var remote *RemoteError
+	if !errors.As(err, &remote) || remote.Address != "/double" || remote.Message == "" {
+		t.Errorf("Call(/double x) = %v, want RemoteError for /double", err)
+	}
+
+	short, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if _, err := client.Call(short, srv.LocalAddr().String(), "/unhandled"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Call(/unhandled) = %v, want: %v", err, context.DeadlineExceeded)
+	}
+}
@@ -0,0 +1,102 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/pfcm/osc"
+)
+
+// Gap describes a break in a sequence of messages, detected by Sequenced.
+type Gap struct {
+	// Key identifies the sequence: the address of the peer that sent the
+	// messages, followed by the message address if sequences are per
+	// address.
+	Key string
+	// Address is the address of the message that revealed the gap.
+	Address string
+	// Want is the sequence number that was expected and Got is the one
+	// received. Got > Want means Got-Want messages were lost, anything else
+	// means messages arrived out of order or the sender restarted.
+	Want, Got int32
+}
+
+// Sequenced wraps a Handler to check the sequence numbers added by an
+// osc.Sequencer. The sequence number is removed before the message is passed
+// on, and onGap is called whenever it is not the one expected. perAddress
+// should match the Sequencer. At most maxSequences sequences are followed, past
+// that the one least recently seen is forgotten.
+func Sequenced(h Handler, perAddress bool, onGap func(Gap)) ContextHandler {
+	return &sequenced{
+		h:          h,
+		perAddress: perAddress,
+		onGap:      onGap,
+		last:       make(map[string]*sequence),
+	}
+}
+
+// maxSequences limits how many sequences Sequenced keeps track of.
+const maxSequences = 1024
+
+type sequence struct {
+	last int32
+	// used orders sequences by when they were last seen.
+	used uint64
+}
+
+type sequenced struct {
+	h          Handler
+	perAddress bool
+	onGap      func(Gap)
+
+	mu   sync.Mutex
+	last map[string]*sequence
+	used uint64
+}
+
+func (s *sequenced) Handle(msg *osc.Message) error {
+	return s.HandleContext(context.Background(), msg)
+}
+
+func (s *sequenced) HandleContext(ctx context.Context, msg *osc.Message) error {
+	seq, ok := osc.SequenceNumber(msg)
+	if !ok {
+		return fmt.Errorf("message has no sequence number: %v", msg)
+	}
+	var key string
+	if meta, ok := MetaFromContext(ctx); ok && meta.Addr != nil {
+		key = meta.Addr.String()
+	}
+	if s.perAddress {
+		key += msg.Pattern
+	}
+
+	s.mu.Lock()
+	q, seen := s.last[key]
+	if !seen {
+		if len(s.last) >= maxSequences {
+			var oldest *string
+			for k, r := range s.last {
+				if oldest == nil || r.used < s.last[*oldest].used {
+					oldest = &k
+				}
+			}
+			delete(s.last, *oldest)
+		}
+		q = new(sequence)
+		s.last[key] = q
+	}
+	last := q.last
+	s.used++
+	q.last, q.used = seq, s.used
+	s.mu.Unlock()
+	if seen && seq != last+1 {
+		s.onGap(Gap{Key: key, Address: msg.Pattern, Want: last + 1, Got: seq})
+	}
+
+	return handle(ctx, s.h, &osc.Message{
+		Pattern:   msg.Pattern,
+		Arguments: msg.Arguments[:len(msg.Arguments)-1],
+	})
+}
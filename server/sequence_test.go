@@ -0,0 +1,85 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/pfcm/osc"
+)
+
+func TestSequenced(t *testing.T) {
+	peer := func(port int) context.Context {
+		return WithMeta(context.Background(), Meta{
+			Addr: &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: port},
+		})
+	}
+	for _, perAddress := range []bool{false, true} {
+		var (
+			gaps    []Gap
+			handled int
+		)
+		h := Sequenced(HandlerFunc(func(msg *osc.Message) error {
+			if len(msg.Arguments) != 0 {
+				t.Errorf("sequence number not removed: %v", msg)
+			}
+			handled++
+			return nil
+		}), perAddress, func(g Gap) {
+			gaps = append(gaps, g)
+		})
+
+		seq := osc.NewSequencer(perAddress)
+		send := func(ctx context.Context, addr string, drop bool) {
+			msg := &osc.Message{Pattern: addr}
+			seq.Stamp(msg)
+			if drop {
+				return
+			}
+			if err := h.HandleContext(ctx, msg); err != nil {
+				t.Errorf("HandleContext(%v): %v", msg, err)
+			}
+		}
+		send(peer(1), "/a", false)
+		send(peer(1), "/b", false)
+		send(peer(1), "/a", true)
+		send(peer(1), "/a", false)
+		// A different peer has its own sequences.
+		send(peer(2), "/a", false)
+
+		if handled != 4 {
+			t.Errorf("perAddress=%t: handled %d messages, want 4", perAddress, handled)
+		}
+		want := []Gap{{Key: "127.0.0.1:1", Address: "/a", Want: 2, Got: 3}}
+		if perAddress {
+			want = []Gap{{Key: "127.0.0.1:1/a", Address: "/a", Want: 1, Got: 2}}
+		}
+		if !reflect.DeepEqual(gaps, want) {
+			t.Errorf("perAddress=%t: gaps = %v, want: %v", perAddress, gaps, want)
+		}
+	}
+	if err := Sequenced(HandlerFunc(func(*osc.Message) error { return nil }), false, func(Gap) {}).Handle(&osc.Message{}); err == nil {
+		t.Error("expected error for message without sequence number")
+	}
+}
+
+func TestSequencedLimit(t *testing.T) {
+	h := Sequenced(HandlerFunc(func(*osc.Message) error { return nil }), true, func(Gap) {})
+	seq := osc.NewSequencer(true)
+	for i := range 2 * maxSequences {
+		msg := &osc.Message{Pattern: fmt.Sprint("/seq/", i)}
+		seq.Stamp(msg)
+		if err := h.HandleContext(context.Background(), msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	last := h.(*sequenced).last
+	if got := len(last); got != maxSequences {
+		t.Errorf("%d sequences seen, %d kept, want: %d", 2*maxSequences, got, maxSequences)
+	}
+	if _, ok := last[fmt.Sprint("/seq/", 2*maxSequences-1)]; !ok {
+		t.Error("latest sequence forgotten, want the oldest forgotten")
+	}
+}
@@ -2,12 +2,21 @@
 package server
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"hash/fnv"
+	"iter"
 	"log"
 	"net"
-
-	"golang.org/x/sync/errgroup"
+	"os"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/pfcm/osc"
 )
@@ -28,98 +37,930 @@ func (h handlerFunc) Handle(m *osc.Message) error {
 	return h(m)
 }
 
+// ContextHandler is a Handler that also wants a context with each message. The
+// context carries the message's Meta. Listeners call HandleContext rather than
+// Handle for any handlers that implement it.
+type ContextHandler interface {
+	Handler
+	HandleContext(context.Context, *osc.Message) error
+}
+
+// ContextHandlerFunc converts a function into a ContextHandler.
+func ContextHandlerFunc(f func(context.Context, *osc.Message) error) ContextHandler {
+	return contextHandlerFunc(f)
+}
+
+type contextHandlerFunc func(context.Context, *osc.Message) error
+
+func (h contextHandlerFunc) Handle(m *osc.Message) error {
+	return h(context.Background(), m)
+}
+
+func (h contextHandlerFunc) HandleContext(ctx context.Context, m *osc.Message) error {
+	return h(ctx, m)
+}
+
+// handle calls the most specific handler method h supports.
+func handle(ctx context.Context, h Handler, msg *osc.Message) error {
+	if ch, ok := h.(ContextHandler); ok {
+		return ch.HandleContext(ctx, msg)
+	}
+	return h.Handle(msg)
+}
+
+// Meta holds information about a received message that isn't part of the
+// message itself.
+type Meta struct {
+	// Addr is the address the message was received from. Its Network
+	// method gives the transport, such as "udp" or "websocket".
+	Addr net.Addr
+	// Received is when the packet containing the message was read.
+	Received time.Time
+
+	// annotations are added by Annotate, and never modified in place, as
+	// the Meta may be shared by handlers running concurrently.
+	annotations map[string]any
+}
+
+// Annotation returns the value added for key by Annotate.
+func (m Meta) Annotation(key string) (any, bool) {
+	v, ok := m.annotations[key]
+	return v, ok
+}
+
+// Annotate returns a context whose Meta has the annotation key set to value,
+// for middleware to pass things like an authenticated identity or a trace ID
+// on to the handlers it wraps. Annotations are never sent anywhere.
+func Annotate(ctx context.Context, key string, value any) context.Context {
+	m, _ := MetaFromContext(ctx)
+	annotations := make(map[string]any, len(m.annotations)+1)
+	for k, v := range m.annotations {
+		annotations[k] = v
+	}
+	annotations[key] = value
+	m.annotations = annotations
+	return WithMeta(ctx, m)
+}
+
+type metaKey struct{}
+
+// MetaFromContext returns the Meta for the message being handled, if the
+// context has one.
+func MetaFromContext(ctx context.Context) (Meta, bool) {
+	m, ok := ctx.Value(metaKey{}).(Meta)
+	return m, ok
+}
+
+// WithMeta returns a context carrying the provided Meta.
+func WithMeta(ctx context.Context, m Meta) context.Context {
+	return context.WithValue(ctx, metaKey{}, m)
+}
+
 // Listener listens to a connection and dispatches messages to registered
 // handlers. Each handler may be called in a separate goroutine, even if they
 // are handling the same message. Note this means even multiple instances of the
 // same handler may be executed concurrently.
+//
+// Handle and Unhandle may be called at any time, from any goroutine, including
+// while the Listener is serving. Each message is dispatched to the handlers
+// registered when its dispatch starts: a message dispatched after Handle
+// returns reaches the new handler, and one dispatched after Unhandle returns
+// doesn't reach the removed ones. A message that was already being dispatched
+// is unaffected by a concurrent change, so a handler may still be called
+// after Unhandle returns.
 type Listener struct {
 	conn net.PacketConn
-	// TODO: this could definitely be more efficient, but is it worth it?
-	handlers []handler
+	// handlers is replaced, never modified in place, so dispatch can read
+	// it without locking while handlersMu is held to change it.
+	handlersMu sync.Mutex
+	handlers   atomic.Pointer[[]handler]
 	// workers sets the number of messages handled in parallel. Note this is
 	// separate to the total number of message handlers running in parallel,
 	// because a message may match many handlers.
-	workers int
+	workers  int
+	queueing Queueing
+	// parseWorkers, if non-zero, is the number of goroutines parsing
+	// packets, separately from the goroutine reading them.
+	parseWorkers   int
+	parseQueueSize int
+	// codec parses messages, if nil osc.ParseMessage is used.
+	codec    *osc.Codec
+	interner *osc.Interner
+	// pool is true if messages should come from osc.AcquireMessage.
+	pool      bool
+	direction MatchDirection
+	// access, if set, limits which handlers each peer can reach.
+	access *Access
+	// mirror, if set, is where to re-send received messages matching
+	// mirrorPatterns, or all of them if there are none.
+	mirror         net.Addr
+	mirrorPatterns []string
+	// lockThread pins the reading goroutine to an OS thread, and
+	// readBuffer, if non-zero, is the socket receive buffer size to ask
+	// for.
+	lockThread bool
+	readBuffer int
+	tracer     Tracer
+	// maxWildcards, if positive, limits the "*"s in message patterns.
+	maxWildcards  int
+	coercion      Coercion
+	normalization Normalization
+	// intercept, if set, is offered every message before dispatch, and
+	// returns true if it has consumed it.
+	intercept func(*osc.Message, Meta) bool
+
+	peersMu sync.Mutex
+	peers   map[string]*Peer
+	// dropped counts messages dropped because their queue was full.
+	dropped atomic.Uint64
+
+	// inShutdown is set by Close and Shutdown, and closed is closed by
+	// Close. active counts running calls to Serve.
+	inShutdown atomic.Bool
+	closed     chan struct{}
+	closeOnce  sync.Once
+	active     atomic.Int64
+
+	// buf is the buffer ServeOne and Messages read into.
+	buf []byte
 }
 
 type handler struct {
 	p string
 	h Handler
+	// pattern is true if p is a valid pattern, used when handler
+	// addresses are treated as patterns.
+	pattern bool
 }
 
-func NewListener(conn net.PacketConn, workers int) *Listener {
-	return &Listener{
+// NewListener returns a Listener reading from conn, which handles up to
+// workers messages at a time.
+func NewListener(conn net.PacketConn, workers int, opts ...Option) *Listener {
+	l := &Listener{
 		conn:    conn,
 		workers: workers,
+		closed:  make(chan struct{}),
+
+		maxWildcards: DefaultMaxWildcards,
+	}
+	for _, o := range opts {
+		o(l)
+	}
+	if l.interner != nil {
+		if l.codec == nil {
+			l.codec = new(osc.Codec)
+		}
+		l.codec = l.codec.WithInterner(l.interner)
+	}
+	return l
+}
+
+// Option configures optional behaviour of a Listener.
+type Option func(*Listener)
+
+// Queueing determines how received messages are queued for the workers.
+type Queueing int
+
+const (
+	// SharedQueue puts every message in a single queue, taken from by all
+	// of the workers. If the queue is full, reading from the connection
+	// waits until there is space. This is the default.
+	SharedQueue Queueing = iota
+	// HashedQueues gives each worker its own queue and chooses the queue
+	// for a message by hashing the address it came from. A burst of
+	// messages from one source only delays the sources sharing its queue.
+	// Messages for a full queue are dropped, rather than holding up the
+	// rest, even when there's only one worker and so one queue.
+	HashedQueues
+	// OrderedQueues hashes messages to per-worker queues like HashedQueues,
+	// but waits for space rather than dropping messages, and also hashes
+	// packets to the parse workers if there are any. Every message from
+	// one source is handled by the same worker, in the order it arrived,
+	// however many workers there are.
+	OrderedQueues
+)
+
+// WithQueueing sets how messages are queued for the workers.
+func WithQueueing(q Queueing) Option {
+	return func(l *Listener) {
+		l.queueing = q
+	}
+}
+
+// WithParseWorkers parses packets in a separate pool of goroutines, fed by a
+// queue of up to queueSize packets. By default packets are parsed as they are
+// read, so if the handlers can't keep up the connection isn't read from until
+// they do. With separate parse workers and a large enough queue, packets keep
+// being read from the connection while the handlers are busy, so bursts don't
+// overflow the operating system's receive buffer.
+func WithParseWorkers(workers, queueSize int) Option {
+	return func(l *Listener) {
+		l.parseWorkers = workers
+		l.parseQueueSize = queueSize
+	}
+}
+
+// WithCodec sets the Codec used to parse received messages, so the Listener
+// can understand non-standard argument types.
+func WithCodec(c *osc.Codec) Option {
+	return func(l *Listener) {
+		l.codec = c
+	}
+}
+
+// WithInterning shares the addresses and string arguments of received
+// messages using in, so a server receiving the same strings over and over
+// doesn't allocate a copy of each. It applies to the Codec from WithCodec, if
+// any, without changing it.
+func WithInterning(in *osc.Interner) Option {
+	return func(l *Listener) {
+		l.interner = in
+	}
+}
+
+// WithMessagePool takes received messages from osc.AcquireMessage and releases
+// them once they have been handled, to avoid allocating a new message every
+// time. Handlers must not keep any reference to a message or its arguments
+// after they return.
+func WithMessagePool() Option {
+	return func(l *Listener) {
+		l.pool = true
+	}
+}
+
+// release returns a message to the pool, if we're using it.
+func (l *Listener) release(msg *osc.Message) {
+	if l.pool {
+		osc.ReleaseMessage(msg)
+	}
+}
+
+// MatchDirection determines which addresses are treated as patterns when
+// matching messages to handlers.
+type MatchDirection int
+
+const (
+	// MessagePattern treats message addresses as patterns, matched against
+	// handler addresses, as in the OSC spec. This is the default.
+	MessagePattern MatchDirection = iota
+	// HandlerPattern treats handler addresses as patterns, matched against
+	// message addresses. A handler address that isn't a valid pattern only
+	// matches the exact same message address.
+	HandlerPattern
+	// EitherPattern treats both addresses as patterns, and matches if
+	// either matches the other.
+	EitherPattern
+)
+
+// WithMatchDirection sets which addresses are treated as patterns. Many
+// programs send wildcards to receivers expecting handlers to hold them, or the
+// other way around.
+func WithMatchDirection(d MatchDirection) Option {
+	return func(l *Listener) {
+		l.direction = d
+	}
+}
+
+// DefaultMaxWildcards is the default limit on "*"s in message patterns.
+const DefaultMaxWildcards = 32
+
+// WithMaxWildcards limits the number of "*"s in the address patterns of
+// received messages, as each one multiplies the work matching a pattern can
+// take. Patterns over the limit are an ErrPatternTooComplex error, or with
+// EitherPattern, are only matched against handler patterns. The default is
+// DefaultMaxWildcards, and n <= 0 means no limit.
+func WithMaxWildcards(n int) Option {
+	return func(l *Listener) {
+		l.maxWildcards = n
+	}
+}
+
+// validate checks a message's address is a valid pattern, within the limits
+// on its complexity.
+func (l *Listener) validate(address string) error {
+	if err := validate(address); err != nil {
+		return err
+	}
+	if n := strings.Count(address, "*"); l.maxWildcards > 0 && n > l.maxWildcards {
+		return fmt.Errorf("%w: %d wildcards, limit %d", ErrPatternTooComplex, n, l.maxWildcards)
+	}
+	return nil
+}
+
+// WithMirror re-sends received messages to addr from the listening connection,
+// so another machine can watch the traffic arriving at this one. If any
+// patterns are provided, only messages with addresses they match are mirrored.
+// Messages are mirrored before being dispatched, including those no handler
+// matches.
+func WithMirror(addr net.Addr, patterns ...string) Option {
+	return func(l *Listener) {
+		l.mirror = addr
+		l.mirrorPatterns = patterns
+	}
+}
+
+// mirrorMessage re-sends msg to l.mirror, if it should be mirrored.
+func (l *Listener) mirrorMessage(msg *osc.Message) {
+	if l.mirror == nil {
+		return
+	}
+	matched := len(l.mirrorPatterns) == 0
+	for _, p := range l.mirrorPatterns {
+		if ok, err := Match(p, msg.Pattern); ok || err != nil && p == msg.Pattern {
+			matched = true
+			break
+		}
+	}
+	if !matched {
+		return
+	}
+	if _, err := l.conn.WriteTo(msg.Append(nil), l.mirror); err != nil {
+		log.Printf("Error mirroring message to %v: %v", l.mirror, err)
+	}
+}
+
+// Peer is a remote endpoint a Listener has received messages from.
+type Peer struct {
+	Addr                net.Addr
+	FirstSeen, LastSeen time.Time
+	// Messages is the number of valid messages received from the peer.
+	Messages uint64
+}
+
+// maxPeers limits how many peers a Listener remembers. When there are more,
+// the one heard from least recently is forgotten.
+const maxPeers = 1024
+
+// track records a message from the peer in meta.
+func (l *Listener) track(meta Meta) {
+	if meta.Addr == nil {
+		return
+	}
+	key := meta.Addr.String()
+	l.peersMu.Lock()
+	defer l.peersMu.Unlock()
+	p, ok := l.peers[key]
+	if !ok {
+		if l.peers == nil {
+			l.peers = make(map[string]*Peer)
+		}
+		if len(l.peers) >= maxPeers {
+			oldest := ""
+			for k, q := range l.peers {
+				if oldest == "" || q.LastSeen.Before(l.peers[oldest].LastSeen) {
+					oldest = k
+				}
+			}
+			delete(l.peers, oldest)
+		}
+		p = &Peer{Addr: meta.Addr, FirstSeen: meta.Received}
+		l.peers[key] = p
+	}
+	p.LastSeen = meta.Received
+	p.Messages++
+}
+
+// Peers returns the peers messages have been received from, in the order they
+// were first seen.
+func (l *Listener) Peers() []Peer {
+	l.peersMu.Lock()
+	defer l.peersMu.Unlock()
+	peers := make([]Peer, 0, len(l.peers))
+	for _, p := range l.peers {
+		peers = append(peers, *p)
+	}
+	slices.SortFunc(peers, func(a, b Peer) int {
+		return a.FirstSeen.Compare(b.FirstSeen)
+	})
+	return peers
+}
+
+// SendTo sends a message to addr from the listening connection, so replies
+// come from the same address and port messages were sent to. This is what
+// most devices expect, and lets replies through NATs and firewalls that have
+// seen the peer's messages.
+func (l *Listener) SendTo(addr net.Addr, msg *osc.Message) error {
+	return l.SendToContext(context.Background(), addr, msg)
+}
+
+// SendToContext is like SendTo, but traces the send in a SendSpan inside any
+// span in the context, such as a handler's when replying.
+func (l *Listener) SendToContext(ctx context.Context, addr net.Addr, msg *osc.Message) error {
+	_, end := l.startSpan(ctx, SendSpan, msg.Pattern)
+	defer end()
+	_, err := l.conn.WriteTo(msg.Append(nil), addr)
+	return err
+}
+
+// WithLockOSThread runs the goroutine reading from the connection on its own OS
+// thread, so the scheduler doesn't move it around. Along with WithMessagePool,
+// which reduces garbage collection, this helps reduce jitter on small machines.
+func WithLockOSThread() Option {
+	return func(l *Listener) {
+		l.lockThread = true
+	}
+}
+
+// WithReadBuffer asks the operating system for a receive buffer of the given
+// number of bytes on the connection, so bursts are buffered rather than
+// dropped while the Listener catches up. Serve returns an error if the
+// connection doesn't support setting it.
+func WithReadBuffer(bytes int) Option {
+	return func(l *Listener) {
+		l.readBuffer = bytes
+	}
+}
+
+// queueSize is the number of messages each queue can hold.
+const queueSize = 100
+
+// queueIndex picks the queue for messages from addr.
+func queueIndex(addr net.Addr, queues int) int {
+	h := fnv.New32a()
+	if addr != nil {
+		h.Write([]byte(addr.String()))
 	}
+	return int(h.Sum32() % uint32(queues))
+}
+
+// Dropped returns the number of messages dropped because there was no space
+// in their queue.
+func (l *Listener) Dropped() uint64 {
+	return l.dropped.Load()
 }
 
-// Handle registers a handler to receive messages on the provided pattern.
+// Handle registers a handler to receive messages on the provided pattern. It's
+// safe to call while serving, see Listener.
 func (l *Listener) Handle(pattern string, h Handler) {
-	l.handlers = append(l.handlers, handler{pattern, h})
+	pattern = l.normalization.normalize(pattern)
+	l.handlersMu.Lock()
+	defer l.handlersMu.Unlock()
+	l.handlers.Store(ptr(append(slices.Clip(l.handlerTable()), handler{
+		p:       pattern,
+		h:       h,
+		pattern: validate(pattern) == nil,
+	})))
+}
+
+// Unhandle removes every handler registered on exactly the provided pattern,
+// reporting whether there were any. It's safe to call while serving, but
+// messages already being dispatched may still reach them, see Listener.
+func (l *Listener) Unhandle(pattern string) bool {
+	pattern = l.normalization.normalize(pattern)
+	l.handlersMu.Lock()
+	defer l.handlersMu.Unlock()
+	old := l.handlerTable()
+	handlers := slices.DeleteFunc(slices.Clone(old), func(h handler) bool {
+		return h.p == pattern
+	})
+	l.handlers.Store(&handlers)
+	return len(handlers) < len(old)
+}
+
+// handlerTable returns the registered handlers, which must not be modified.
+func (l *Listener) handlerTable() []handler {
+	if h := l.handlers.Load(); h != nil {
+		return *h
+	}
+	return nil
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+// matches reports whether a message address matches a handler, per
+// l.direction. valid says whether the message address is a valid pattern.
+func (l *Listener) matches(addr string, valid bool, h handler) bool {
+	handlerMatch := func() bool {
+		if !h.pattern {
+			// Invalid patterns can only match literally.
+			return h.p == addr
+		}
+		return match(h.p, addr)
+	}
+	switch l.direction {
+	case HandlerPattern:
+		return handlerMatch()
+	case EitherPattern:
+		return valid && match(addr, h.p) || handlerMatch()
+	}
+	return match(addr, h.p)
+}
+
+// RegisteredHandler is a handler and the address it was registered on.
+type RegisteredHandler struct {
+	Address string
+	Handler Handler
+}
+
+// MatchesFor returns the handlers a message sent to address would be dispatched
+// to, in the order they would be called. It's meant for checking registrations
+// catch the addresses a device will send.
+func (l *Listener) MatchesFor(address string) []RegisteredHandler {
+	address = l.normalization.normalize(address)
+	err := l.validate(address)
+	if err != nil && l.direction == MessagePattern {
+		return nil
+	}
+	var matched []RegisteredHandler
+	for _, h := range l.handlerTable() {
+		if l.matches(address, err == nil, h) {
+			matched = append(matched, RegisteredHandler{h.p, h.h})
+		}
+	}
+	return matched
+}
+
+// packet is a received message on its way to the workers.
+type packet struct {
+	msg  *osc.Message
+	meta Meta
 }
 
 // handle actually dispatches an individual message to each of the applicable
 // Handlers.
-func (l *Listener) handle(msg *osc.Message) error {
-	pattern, err := ParsePattern(msg.Pattern)
-	if err != nil {
+func (l *Listener) handle(ctx context.Context, p packet) error {
+	msg := p.msg
+	l.track(p.meta)
+	l.mirrorMessage(msg)
+	msg.Pattern = l.normalization.normalize(msg.Pattern)
+	if l.intercept != nil && l.intercept(msg, p.meta) {
+		return nil
+	}
+	err := l.validate(msg.Pattern)
+	if err != nil && l.direction == MessagePattern {
 		return err
 	}
-	for _, m := range l.handlers {
-		if pattern.Match(m.p) {
+	valid := err == nil
+	ctx = withCoercion(WithMeta(ctx, p.meta), l.coercion)
+	ctx, end := l.startSpan(ctx, DispatchSpan, msg.Pattern)
+	defer end()
+	for _, m := range l.handlerTable() {
+		if l.matches(msg.Pattern, valid, m) {
+			if l.access != nil && !l.access.Allowed(p.meta.Addr, m.p) {
+				continue
+			}
+			hctx, end := l.startSpan(ctx, HandlerSpan, m.p)
 			// TODO: do these concurrently?
-			if err := m.h.Handle(msg); err != nil {
+			if err := handle(hctx, m.h, msg); err != nil {
 				log.Printf("Error from handler %q: %v (message: %v)", m.p, err, msg)
 			}
+			end()
 		}
 	}
 	return nil
 }
 
+// raw is a received packet before parsing.
+type raw struct {
+	b    []byte
+	meta Meta
+}
+
+// parseMessage parses a raw packet with l.codec, taking the message from the
+// pool if we're using it.
+func (l *Listener) parseMessage(b []byte) (*osc.Message, error) {
+	parseInto := osc.ParseMessageInto
+	if l.codec != nil {
+		parseInto = l.codec.ParseMessageInto
+	}
+	msg := new(osc.Message)
+	if l.pool {
+		msg = osc.AcquireMessage()
+	}
+	if err := parseInto(msg, b); err != nil {
+		l.release(msg)
+		return nil, err
+	}
+	return msg, nil
+}
+
+// parse parses a raw packet and queues the message for the workers. Invalid
+// packets are logged and dropped.
+func (l *Listener) parse(ctx context.Context, queues []chan packet, r raw) error {
+	msg, err := l.parseMessage(r.b)
+	if err != nil {
+		log.Printf("Received invalid message from %v: %v", r.meta.Addr, err)
+		return nil
+	}
+	return l.enqueue(ctx, queues, packet{msg, r.meta})
+}
+
+// enqueue adds a packet to the appropriate queue for l.queueing.
+func (l *Listener) enqueue(ctx context.Context, queues []chan packet, p packet) error {
+	q := queues[0]
+	if len(queues) > 1 {
+		q = queues[queueIndex(p.meta.Addr, len(queues))]
+	}
+	// HashedQueues drops messages for a full queue even if there's only
+	// one, the others wait for space.
+	if l.queueing != HashedQueues {
+		select {
+		case q <- p:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	select {
+	case q <- p:
+	default:
+		l.dropped.Add(1)
+		l.release(p.msg)
+	}
+	return nil
+}
+
+// ErrListenerClosed is returned by Serve after a call to Close or Shutdown.
+var ErrListenerClosed = errors.New("server: Listener closed")
+
 // Serve starts listening to OSC packets and dispatching them to registered
 // handlers. It blocks until the context is cancelled or it receives an error
-// from the underlying connection.
+// from the underlying connection. Cancelling the context sets a read deadline on
+// the connection, so Serve returns promptly even if no more packets arrive.
+// After Close or Shutdown, Serve returns ErrListenerClosed.
 func (l *Listener) Serve(ctx context.Context) error {
-	recv := make(chan *osc.Message, 100)
-	g, gctx := errgroup.WithContext(ctx)
+	l.active.Add(1)
+	defer l.active.Add(-1)
+	if l.inShutdown.Load() {
+		return ErrListenerClosed
+	}
+	queues := make([]chan packet, 1)
+	if l.queueing == HashedQueues || l.queueing == OrderedQueues {
+		queues = make([]chan packet, max(l.workers, 1))
+	}
+	for i := range queues {
+		queues[i] = make(chan packet, queueSize)
+	}
+	// Clear any deadline left over from a previous call.
+	if err := l.conn.SetReadDeadline(time.Time{}); err != nil {
+		return err
+	}
+	if l.readBuffer > 0 {
+		c, ok := l.conn.(interface{ SetReadBuffer(int) error })
+		if !ok {
+			return fmt.Errorf("can't set the read buffer of a %T", l.conn)
+		}
+		if err := c.SetReadBuffer(l.readBuffer); err != nil {
+			return err
+		}
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-l.closed:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	g, gctx := newGroup(ctx)
+	// ReadFrom doesn't know about the context, so when it's done we set a
+	// deadline in the past to wake the reader up. We wait for this before
+	// returning, so it can't clobber a later call's deadline.
+	woken := make(chan struct{})
+	go func() {
+		defer close(woken)
+		<-gctx.Done()
+		l.conn.SetReadDeadline(time.Now())
+	}()
+	// readDone is closed when the reader stops because of Shutdown, and
+	// stopped once nothing more will be added to the queues, so the workers
+	// know to finish what's queued and return.
+	readDone := make(chan struct{})
+	stopped := readDone
+	// Parse in the reading goroutine, unless there are separate parse
+	// workers.
+	parse := func(r raw) error {
+		return l.parse(gctx, queues, r)
+	}
+	if l.parseWorkers > 0 {
+		// Packets from the same source go to the same parse worker, if
+		// they need to stay in order.
+		raws := make([]chan raw, 1)
+		if l.queueing == OrderedQueues {
+			raws = make([]chan raw, l.parseWorkers)
+		}
+		for i := range raws {
+			raws[i] = make(chan raw, l.parseQueueSize)
+		}
+		parse = func(r raw) error {
+			// The reading goroutine reuses its buffer.
+			r.b = bytes.Clone(r.b)
+			select {
+			case raws[queueIndex(r.meta.Addr, len(raws))] <- r:
+				return nil
+			case <-gctx.Done():
+				return gctx.Err()
+			}
+		}
+		var parsers sync.WaitGroup
+		for i := range l.parseWorkers {
+			recv := raws[i%len(raws)]
+			parsers.Add(1)
+			g.Go(func() error {
+				defer parsers.Done()
+				for {
+					var r raw
+					select {
+					case <-gctx.Done():
+						return gctx.Err()
+					case r = <-recv:
+					case <-readDone:
+						select {
+						case r = <-recv:
+						default:
+							return nil
+						}
+					}
+					if err := l.parse(gctx, queues, r); err != nil {
+						return err
+					}
+				}
+			})
+		}
+		stopped = make(chan struct{})
+		go func() {
+			parsers.Wait()
+			close(stopped)
+		}()
+	}
 	g.Go(func() error {
+		if l.lockThread {
+			runtime.LockOSThread()
+			defer runtime.UnlockOSThread()
+		}
 		buf := make([]byte, 1<<16) // ~max UDP packet size.
 		for {
 			n, addr, err := l.conn.ReadFrom(buf)
+			received := time.Now()
 			if n > 0 {
-				msg, err := osc.ParseMessage(buf[:n])
-				if err != nil {
-					log.Printf("Received invalid message from %v: %v", addr, err)
-				}
-				select {
-				case recv <- msg:
-				case <-gctx.Done():
-					return gctx.Err()
+				if err := parse(raw{buf[:n], Meta{Addr: addr, Received: received}}); err != nil {
+					return err
 				}
 			}
 			if err != nil {
+				if l.inShutdown.Load() {
+					close(readDone)
+					return nil
+				}
+				if gctx.Err() != nil {
+					return gctx.Err()
+				}
 				return err
 			}
 		}
 	})
-	for range l.workers {
+	for i := range l.workers {
+		recv := queues[i%len(queues)]
 		g.Go(func() error {
 			for {
-				var msg *osc.Message
+				var p packet
 				select {
 				case <-gctx.Done():
 					return gctx.Err()
-				case msg = <-recv:
+				case p = <-recv:
+				case <-stopped:
+					select {
+					case p = <-recv:
+					default:
+						return nil
+					}
 				}
-				if err := l.handle(msg); err != nil {
-					log.Printf("Error handling message: %v (message: %v)", err, msg)
+				if err := l.handle(gctx, p); err != nil {
+					log.Printf("Error handling message: %v (message: %v)", err, p.msg)
 				}
+				l.release(p.msg)
 			}
 		})
 	}
 
-	return g.Wait()
+	err := g.Wait()
+	<-woken
+	if l.inShutdown.Load() {
+		return ErrListenerClosed
+	}
+	return err
+}
+
+// ServeOne reads a single packet from the connection and dispatches it to the
+// matching handlers in the calling goroutine, for programs with their own event
+// loop. It waits for a packet until the context is done, so a context with a
+// short deadline can be used to poll. The workers, queueing and parse worker
+// options have no effect, and ServeOne must not be called concurrently with
+// itself, Serve or Messages.
+func (l *Listener) ServeOne(ctx context.Context) error {
+	b, meta, err := l.read(ctx)
+	if err != nil {
+		return err
+	}
+	msg, err := l.parseMessage(b)
+	if err != nil {
+		return fmt.Errorf("invalid message from %v: %w", meta.Addr, err)
+	}
+	defer l.release(msg)
+	return l.handle(ctx, packet{msg, meta})
+}
+
+// Messages returns an iterator over the messages read from the connection, as
+// an alternative to registering handlers. Handlers are not called. Iteration
+// stops when the context is done, the Listener is closed, or there's an error
+// reading from the connection, which is logged. Invalid packets are logged and
+// skipped. With WithMessagePool, each message is released once the loop body
+// returns. As with ServeOne, Messages must not be used concurrently with
+// itself, Serve or ServeOne.
+func (l *Listener) Messages(ctx context.Context) iter.Seq2[*osc.Message, Meta] {
+	return func(yield func(*osc.Message, Meta) bool) {
+		for {
+			b, meta, err := l.read(ctx)
+			if err != nil {
+				if err != ErrListenerClosed && ctx.Err() == nil {
+					log.Printf("Error reading messages: %v", err)
+				}
+				return
+			}
+			msg, err := l.parseMessage(b)
+			if err != nil {
+				log.Printf("Received invalid message from %v: %v", meta.Addr, err)
+				continue
+			}
+			l.track(meta)
+			more := yield(msg, meta)
+			l.release(msg)
+			if !more {
+				return
+			}
+		}
+	}
+}
+
+// read reads a single packet into l.buf, waiting until the context is done.
+func (l *Listener) read(ctx context.Context) ([]byte, Meta, error) {
+	if l.inShutdown.Load() {
+		return nil, Meta{}, ErrListenerClosed
+	}
+	deadline, _ := ctx.Deadline()
+	if err := l.conn.SetReadDeadline(deadline); err != nil {
+		return nil, Meta{}, err
+	}
+	woken := make(chan struct{})
+	stop := context.AfterFunc(ctx, func() {
+		l.conn.SetReadDeadline(time.Now())
+		close(woken)
+	})
+	if l.buf == nil {
+		l.buf = make([]byte, 1<<16) // ~max UDP packet size.
+	}
+	n, addr, err := l.conn.ReadFrom(l.buf)
+	received := time.Now()
+	if !stop() {
+		// Make sure the deadline is set before the next call clears it.
+		<-woken
+	}
+	if err != nil {
+		switch {
+		case l.inShutdown.Load():
+			return nil, Meta{}, ErrListenerClosed
+		case ctx.Err() != nil:
+			return nil, Meta{}, ctx.Err()
+		case !deadline.IsZero() && errors.Is(err, os.ErrDeadlineExceeded):
+			// The connection noticed the deadline before the context.
+			return nil, Meta{}, context.DeadlineExceeded
+		}
+		return nil, Meta{}, err
+	}
+	return l.buf[:n], Meta{Addr: addr, Received: received}, nil
+}
+
+// Close closes the connection immediately, without waiting for queued messages
+// to be handled. Serve returns ErrListenerClosed.
+func (l *Listener) Close() error {
+	l.inShutdown.Store(true)
+	l.closeOnce.Do(func() { close(l.closed) })
+	return l.conn.Close()
+}
+
+// shutdownPollInterval is how often Shutdown checks whether Serve has returned.
+const shutdownPollInterval = 10 * time.Millisecond
+
+// Shutdown closes the connection, then waits for any messages already received
+// to be handled and Serve to return ErrListenerClosed. If the context is done
+// first, Shutdown returns its error, and Close can be used to stop waiting for
+// the handlers.
+func (l *Listener) Shutdown(ctx context.Context) error {
+	l.inShutdown.Store(true)
+	err := l.conn.Close()
+	t := time.NewTicker(shutdownPollInterval)
+	defer t.Stop()
+	for l.active.Load() > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+	return err
 }
 
 type UnmatchedPatternError struct {
@@ -0,0 +1,621 @@
+package server
+
+import (
+	"context"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pfcm/osc"
+)
+
+func listen(t *testing.T) net.PacketConn {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func serve(t *testing.T, l *Listener) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go l.Serve(ctx)
+}
+
+func send(t *testing.T, conn net.PacketConn, to net.Addr, pattern string, args ...osc.Argument) {
+	t.Helper()
+	if err := osc.Send(conn, to.String(), pattern, args...); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHashedQueues(t *testing.T) {
+	const workers = 2
+	conn := listen(t)
+	l := NewListener(conn, workers, WithQueueing(HashedQueues))
+	release := make(chan struct{})
+	defer close(release)
+	l.Handle("/slow", HandlerFunc(func(*osc.Message) error {
+		<-release
+		return nil
+	}))
+	fast := make(chan struct{}, 1)
+	l.Handle("/fast", HandlerFunc(func(*osc.Message) error {
+		fast <- struct{}{}
+		return nil
+	}))
+	serve(t, l)
+
+	// Find two sources that hash to different queues.
+	noisy := listen(t)
+	quiet := listen(t)
+	for queueIndex(quiet.LocalAddr(), workers) == queueIndex(noisy.LocalAddr(), workers) {
+		quiet = listen(t)
+	}
+
+	// With a shared queue, these would tie up both workers.
+	send(t, noisy, conn.LocalAddr(), "/slow")
+	send(t, noisy, conn.LocalAddr(), "/slow")
+	send(t, quiet, conn.LocalAddr(), "/fast")
+	select {
+	case <-fast:
+	case <-time.After(5 * time.Second):
+		t.Error("message from quiet source held up by noisy source")
+	}
+}
+
+func TestHashedQueuesOneWorker(t *testing.T) {
+	l := NewListener(nil, 1, WithQueueing(HashedQueues))
+	queues := []chan packet{make(chan packet, 1)}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for range 2 {
+		if err := l.enqueue(ctx, queues, packet{msg: &osc.Message{Pattern: "/full"}}); err != nil {
+			t.Fatalf("enqueue to a full queue = %v, want it dropped", err)
+		}
+	}
+	if got := l.Dropped(); got != 1 {
+		t.Errorf("Dropped() = %d, want: 1", got)
+	}
+}
+
+func TestHandleWhileServing(t *testing.T) {
+	conn := listen(t)
+	l := NewListener(conn, 4)
+	serve(t, l)
+	client := listen(t)
+
+	// Churn the handler table while messages are dispatched.
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		nop := HandlerFunc(func(*osc.Message) error { return nil })
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			l.Handle("/churn", nop)
+			l.MatchesFor("/churn")
+			l.Unhandle("/churn")
+		}
+	}()
+	defer func() {
+		close(stop)
+		wg.Wait()
+	}()
+
+	got := make(chan int32)
+	for i := range int32(20) {
+		// A handler registered before the message is sent must see it.
+		l.Handle("/visible", HandlerFunc(func(msg *osc.Message) error {
+			got <- int32(*msg.Arguments[0].(*osc.Int32))
+			return nil
+		}))
+		send(t, client, conn.LocalAddr(), "/churn", osc.AsInt32(i))
+		send(t, client, conn.LocalAddr(), "/visible", osc.AsInt32(i))
+		select {
+		case n := <-got:
+			if n != i {
+				t.Errorf("handler got message %d, want: %d", n, i)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("message %d not dispatched to handler registered while serving", i)
+		}
+		if !l.Unhandle("/visible") {
+			t.Fatal(`Unhandle("/visible") = false, want: true`)
+		}
+	}
+}
+
+func TestParseWorkers(t *testing.T) {
+	conn := listen(t)
+	l := NewListener(conn, 1, WithParseWorkers(2, 1000))
+	const n = 100
+	got := make(chan int32, n)
+	l.Handle("/count", HandlerFunc(func(msg *osc.Message) error {
+		got <- int32(*msg.Arguments[0].(*osc.Int32))
+		return nil
+	}))
+	serve(t, l)
+
+	client := listen(t)
+	for i := range n {
+		send(t, client, conn.LocalAddr(), "/count", osc.AsInt32(i))
+		// Invalid packets shouldn't stop anything.
+		client.WriteTo([]byte("garbage"), conn.LocalAddr())
+	}
+	seen := make(map[int32]bool)
+	for range n {
+		select {
+		case i := <-got:
+			seen[i] = true
+		case <-time.After(5 * time.Second):
+			t.Fatalf("only received %d messages", len(seen))
+		}
+	}
+	if len(seen) != n {
+		t.Errorf("received %d distinct messages, want %d", len(seen), n)
+	}
+}
+
+// vendorInt is a made up argument type.
+type vendorInt struct{ osc.Int32 }
+
+func (vendorInt) TypeTag() rune { return 'v' }
+
+func TestWithCodec(t *testing.T) {
+	conn := listen(t)
+	c := osc.NewCodec()
+	c.Register('v', func() osc.Argument { return new(vendorInt) })
+	l := NewListener(conn, 1, WithCodec(c))
+	got := make(chan *osc.Message, 1)
+	l.Handle("/vendor", HandlerFunc(func(msg *osc.Message) error {
+		got <- msg
+		return nil
+	}))
+	serve(t, l)
+
+	send(t, listen(t), conn.LocalAddr(), "/vendor", &vendorInt{3})
+	select {
+	case msg := <-got:
+		if err := msg.CheckTypes("v"); err != nil {
+			t.Error(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("message with vendor type not received")
+	}
+}
+
+func TestWithInterning(t *testing.T) {
+	conn := listen(t)
+	c := osc.NewCodec()
+	c.Register('v', func() osc.Argument { return new(vendorInt) })
+	in := osc.NewInterner(10)
+	l := NewListener(conn, 1, WithCodec(c), WithInterning(in))
+	got := make(chan string, 2)
+	l.Handle("/mode", HandlerFunc(func(msg *osc.Message) error {
+		got <- string(*msg.Arguments[0].(*osc.String))
+		return nil
+	}))
+	serve(t, l)
+
+	client := listen(t)
+	for range 2 {
+		// The Codec's vendor type is still understood.
+		send(t, client, conn.LocalAddr(), "/mode", osc.AsString("live"), &vendorInt{1})
+		select {
+		case s := <-got:
+			if s != "live" {
+				t.Errorf("received %q, want: live", s)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("message not received")
+		}
+	}
+	if n := in.Len(); n != 2 {
+		t.Errorf("Interner holds %d strings, want: 2 (the address and argument)", n)
+	}
+}
+
+func TestMessagePool(t *testing.T) {
+	conn := listen(t)
+	l := NewListener(conn, 2, WithMessagePool())
+	const n = 50
+	got := make(chan int32, n)
+	l.Handle("/pooled", HandlerFunc(func(msg *osc.Message) error {
+		// Copy the value out, the message is reused once we return.
+		got <- int32(*msg.Arguments[0].(*osc.Int32))
+		return nil
+	}))
+	serve(t, l)
+
+	client := listen(t)
+	for i := range n {
+		send(t, client, conn.LocalAddr(), "/pooled", osc.AsInt32(i))
+	}
+	seen := make(map[int32]bool)
+	for range n {
+		select {
+		case i := <-got:
+			seen[i] = true
+		case <-time.After(5 * time.Second):
+			t.Fatalf("only received %d messages", len(seen))
+		}
+	}
+	if len(seen) != n {
+		t.Errorf("received %d distinct values, want %d", len(seen), n)
+	}
+}
+
+func TestServeCancel(t *testing.T) {
+	l := NewListener(listen(t), 1)
+	for range 2 {
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error)
+		go func() { done <- l.Serve(ctx) }()
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+		select {
+		case err := <-done:
+			if err != context.Canceled {
+				t.Errorf("Serve() = %v, want: %v", err, context.Canceled)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Serve did not return after the context was cancelled")
+		}
+	}
+}
+
+func TestClose(t *testing.T) {
+	l := NewListener(listen(t), 1)
+	done := make(chan error)
+	go func() { done <- l.Serve(context.Background()) }()
+	time.Sleep(10 * time.Millisecond)
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-done:
+		if err != ErrListenerClosed {
+			t.Errorf("Serve() = %v, want: %v", err, ErrListenerClosed)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Serve did not return after Close")
+	}
+	if err := l.Serve(context.Background()); err != ErrListenerClosed {
+		t.Errorf("Serve() after Close = %v, want: %v", err, ErrListenerClosed)
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	for _, opts := range [][]Option{
+		nil,
+		{WithParseWorkers(2, 100)},
+		{WithQueueing(HashedQueues)},
+	} {
+		conn := listen(t)
+		l := NewListener(conn, 2, opts...)
+		const n = 10
+		started, unblock := make(chan struct{}, n), make(chan struct{})
+		var handled atomic.Int32
+		l.Handle("/slow", HandlerFunc(func(*osc.Message) error {
+			started <- struct{}{}
+			<-unblock
+			handled.Add(1)
+			return nil
+		}))
+		done := make(chan error)
+		go func() { done <- l.Serve(context.Background()) }()
+
+		client := listen(t)
+		for range n {
+			send(t, client, conn.LocalAddr(), "/slow")
+		}
+		<-started
+		// Give the rest of the packets time to be read.
+		time.Sleep(50 * time.Millisecond)
+		shutdown := make(chan error)
+		go func() { shutdown <- l.Shutdown(context.Background()) }()
+		time.Sleep(10 * time.Millisecond)
+		close(unblock)
+
+		if err := <-shutdown; err != nil {
+			t.Errorf("Shutdown() = %v, want: nil", err)
+		}
+		if err := <-done; err != ErrListenerClosed {
+			t.Errorf("Serve() = %v, want: %v", err, ErrListenerClosed)
+		}
+		if got := handled.Load(); got != n {
+			t.Errorf("handled %d messages before shutting down, want: %d", got, n)
+		}
+	}
+}
+
+func TestServeOne(t *testing.T) {
+	conn := listen(t)
+	l := NewListener(conn, 0)
+	var got []int32
+	l.Handle("/count", HandlerFunc(func(msg *osc.Message) error {
+		got = append(got, int32(*msg.Arguments[0].(*osc.Int32)))
+		return nil
+	}))
+
+	// Nothing has been sent yet, so polling should time out.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	if err := l.ServeOne(ctx); err != context.DeadlineExceeded {
+		t.Errorf("ServeOne() with nothing to read = %v, want: %v", err, context.DeadlineExceeded)
+	}
+	cancel()
+
+	client := listen(t)
+	const n = 5
+	for i := range n {
+		send(t, client, conn.LocalAddr(), "/count", osc.AsInt32(i))
+	}
+	client.WriteTo([]byte("garbage"), conn.LocalAddr())
+	for range n {
+		if err := l.ServeOne(context.Background()); err != nil {
+			t.Fatalf("ServeOne(): %v", err)
+		}
+	}
+	for i, c := range got {
+		if c != int32(i) {
+			t.Errorf("message %d = %d, want: %d", i, c, i)
+		}
+	}
+	if len(got) != n {
+		t.Errorf("handled %d messages, want: %d", len(got), n)
+	}
+	if err := l.ServeOne(context.Background()); err == nil {
+		t.Error("ServeOne() with invalid packet = nil, want error")
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	if err := l.ServeOne(ctx); err != context.Canceled {
+		t.Errorf("ServeOne() after cancel = %v, want: %v", err, context.Canceled)
+	}
+}
+
+func TestMessages(t *testing.T) {
+	conn := listen(t)
+	l := NewListener(conn, 0, WithMessagePool())
+	client := listen(t)
+	const n = 5
+	for i := range n {
+		send(t, client, conn.LocalAddr(), "/count", osc.AsInt32(i))
+		client.WriteTo([]byte("garbage"), conn.LocalAddr())
+	}
+
+	var got []int32
+	for msg, meta := range l.Messages(context.Background()) {
+		if meta.Addr.String() != client.LocalAddr().String() {
+			t.Errorf("message from %v, want: %v", meta.Addr, client.LocalAddr())
+		}
+		got = append(got, int32(*msg.Arguments[0].(*osc.Int32)))
+		if len(got) == n {
+			break
+		}
+	}
+	for i, c := range got {
+		if c != int32(i) {
+			t.Errorf("message %d = %d, want: %d", i, c, i)
+		}
+	}
+
+	// Nothing else has been sent, so this should stop at the deadline.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	for msg := range l.Messages(ctx) {
+		t.Errorf("unexpected message %v", msg)
+	}
+}
+
+func TestMirror(t *testing.T) {
+	monitor := listen(t)
+	conn := listen(t)
+	l := NewListener(conn, 1, WithMirror(monitor.LocalAddr(), "/mix/*", "/[odd"))
+	serve(t, l)
+
+	client := listen(t)
+	for _, p := range []string{"/other", "/mix/1", "/[odd", "/mix/2"} {
+		send(t, client, conn.LocalAddr(), p, osc.AsInt32(1))
+	}
+	buf := make([]byte, 1024)
+	for _, want := range []string{"/mix/1", "/[odd", "/mix/2"} {
+		monitor.SetReadDeadline(time.Now().Add(5 * time.Second))
+		n, from, err := monitor.ReadFrom(buf)
+		if err != nil {
+			t.Fatalf("waiting for mirrored %s: %v", want, err)
+		}
+		if from.String() != conn.LocalAddr().String() {
+			t.Errorf("mirrored message from %v, want: %v", from, conn.LocalAddr())
+		}
+		msg, err := osc.ParseMessage(buf[:n])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if msg.Pattern != want {
+			t.Errorf("mirrored message %v, want address: %s", msg, want)
+		}
+	}
+}
+
+func TestPeers(t *testing.T) {
+	conn := listen(t)
+	l := NewListener(conn, 1)
+	got := make(chan net.Addr, 10)
+	l.Handle("/ping", ContextHandlerFunc(func(ctx context.Context, msg *osc.Message) error {
+		meta, _ := MetaFromContext(ctx)
+		got <- meta.Addr
+		return l.SendTo(meta.Addr, &osc.Message{Pattern: "/pong"})
+	}))
+	serve(t, l)
+
+	a, b := listen(t), listen(t)
+	for _, c := range []net.PacketConn{a, b, a} {
+		send(t, c, conn.LocalAddr(), "/ping")
+		select {
+		case <-got:
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for /ping")
+		}
+		// The reply should come from the address we sent to.
+		c.SetReadDeadline(time.Now().Add(5 * time.Second))
+		buf := make([]byte, 64)
+		if _, from, err := c.ReadFrom(buf); err != nil || from.String() != conn.LocalAddr().String() {
+			t.Errorf("reply from %v (err %v), want: %v", from, err, conn.LocalAddr())
+		}
+	}
+
+	peers := l.Peers()
+	if len(peers) != 2 {
+		t.Fatalf("Peers() returned %d peers, want: 2", len(peers))
+	}
+	for i, c := range []struct {
+		addr     net.Addr
+		messages uint64
+	}{{a.LocalAddr(), 2}, {b.LocalAddr(), 1}} {
+		if p := peers[i]; p.Addr.String() != c.addr.String() || p.Messages != c.messages {
+			t.Errorf("peer %d = %+v, want %v with %d messages", i, p, c.addr, c.messages)
+		}
+	}
+}
+
+func TestOrderedQueues(t *testing.T) {
+	conn := listen(t)
+	l := NewListener(conn, 8, WithQueueing(OrderedQueues), WithParseWorkers(4, 100))
+	const sources, n = 3, 50
+	var (
+		mu   sync.Mutex
+		got  = make(map[string][]int32)
+		done = make(chan struct{}, sources*n)
+	)
+	l.Handle("/seq", ContextHandlerFunc(func(ctx context.Context, msg *osc.Message) error {
+		meta, _ := MetaFromContext(ctx)
+		mu.Lock()
+		defer mu.Unlock()
+		got[meta.Addr.String()] = append(got[meta.Addr.String()], int32(*msg.Arguments[0].(*osc.Int32)))
+		done <- struct{}{}
+		return nil
+	}))
+	serve(t, l)
+
+	var clients []net.PacketConn
+	for range sources {
+		clients = append(clients, listen(t))
+	}
+	for i := range n {
+		for _, c := range clients {
+			send(t, c, conn.LocalAddr(), "/seq", osc.AsInt32(i))
+		}
+	}
+	for i := range sources * n {
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for messages, got %d of %d", i, sources*n)
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	for _, c := range clients {
+		seq := got[c.LocalAddr().String()]
+		for i, v := range seq {
+			if v != int32(i) {
+				t.Fatalf("message %d from %v = %d, want: %d", i, c.LocalAddr(), v, i)
+			}
+		}
+	}
+}
+
+func TestReadBuffer(t *testing.T) {
+	conn := listen(t)
+	l := NewListener(conn, 1, WithReadBuffer(1<<20), WithLockOSThread())
+	got := make(chan struct{}, 1)
+	l.Handle("/ping", HandlerFunc(func(*osc.Message) error {
+		got <- struct{}{}
+		return nil
+	}))
+	serve(t, l)
+	send(t, listen(t), conn.LocalAddr(), "/ping")
+	select {
+	case <-got:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for /ping")
+	}
+
+	// A wrapped connection doesn't expose SetReadBuffer.
+	l = NewListener(struct{ net.PacketConn }{listen(t)}, 1, WithReadBuffer(1<<20))
+	if err := l.Serve(context.Background()); err == nil {
+		t.Error("Serve() with unsupported read buffer = nil, want error")
+	}
+}
+
+func TestListenUDP(t *testing.T) {
+	l, err := ListenUDP(context.Background(), "127.0.0.1:0", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.conn.Close() })
+	if l.readBuffer != DefaultReadBuffer {
+		t.Errorf("readBuffer = %d, want: %d", l.readBuffer, DefaultReadBuffer)
+	}
+	got := make(chan struct{}, 1)
+	l.Handle("/ping", HandlerFunc(func(*osc.Message) error {
+		got <- struct{}{}
+		return nil
+	}))
+	serve(t, l)
+	send(t, listen(t), l.LocalAddr(), "/ping")
+	select {
+	case <-got:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for /ping")
+	}
+
+	l, err = ListenUDP(context.Background(), "127.0.0.1:0", 1, WithReadBuffer(4096))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.conn.Close()
+	if l.readBuffer != 4096 {
+		t.Errorf("readBuffer with WithReadBuffer(4096) = %d, want: 4096", l.readBuffer)
+	}
+}
+
+func TestAnnotate(t *testing.T) {
+	addr := &net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 9000}
+	ctx := WithMeta(context.Background(), Meta{Addr: addr})
+	authed := Annotate(ctx, "identity", "desk")
+	traced := Annotate(authed, "trace", 42)
+
+	meta, _ := MetaFromContext(traced)
+	if meta.Addr != addr {
+		t.Errorf("annotated Meta.Addr = %v, want: %v", meta.Addr, addr)
+	}
+	for key, want := range map[string]any{"identity": "desk", "trace": 42} {
+		if got, ok := meta.Annotation(key); !ok || got != want {
+			t.Errorf("Annotation(%q) = %v, %t, want: %v, true", key, got, ok, want)
+		}
+	}
+	// Earlier contexts aren't changed.
+	meta, _ = MetaFromContext(authed)
+	if _, ok := meta.Annotation("trace"); ok {
+		t.Error(`Annotation("trace") set on the parent context`)
+	}
+	meta, _ = MetaFromContext(ctx)
+	if _, ok := meta.Annotation("identity"); ok {
+		t.Error(`Annotation("identity") set on the original context`)
+	}
+}
@@ -0,0 +1,254 @@
+package server
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pfcm/osc"
+)
+
+// Stats collects statistics about received messages for each address, and
+// about calls to handlers for each label given to Labelled.
+type Stats struct {
+	mu     sync.Mutex
+	addrs  map[string]*addressStats
+	labels map[string]*labelStats
+}
+
+// AddressStats are the statistics for a single address.
+type AddressStats struct {
+	Address string
+	// Received is the number of messages received.
+	Received uint64
+	// Lost is the number of messages lost, as reported to Stats.Gap.
+	Lost uint64
+	// Rate is the average number of messages received per second.
+	Rate float64
+	// Jitter is the average deviation of the time between messages from
+	// its mean.
+	Jitter time.Duration
+}
+
+// Loss returns the fraction of messages that were lost.
+func (a AddressStats) Loss() float64 {
+	if total := a.Received + a.Lost; total > 0 {
+		return float64(a.Lost) / float64(total)
+	}
+	return 0
+}
+
+type addressStats struct {
+	AddressStats
+	last     time.Time
+	interval float64 // smoothed seconds between messages
+	jitter   float64 // smoothed deviation from interval, in seconds
+}
+
+// LabelStats are the statistics for the handlers sharing a label.
+type LabelStats struct {
+	Label string
+	// Matched is the number of messages passed to the handlers.
+	Matched uint64
+	// Errors is the number of calls that returned an error.
+	Errors uint64
+	// Latency is the mean time taken by each call, and MaxLatency the
+	// longest.
+	Latency    time.Duration
+	MaxLatency time.Duration
+}
+
+type labelStats struct {
+	LabelStats
+	total time.Duration
+}
+
+// maxAddresses limits how many addresses Stats keeps statistics for. When
+// there are more, the address least recently received is forgotten.
+const maxAddresses = 1024
+
+// smoothing is the weight of each new sample in the moving averages, the same
+// as used for jitter in RTP (RFC 3550).
+const smoothing = 1.0 / 16
+
+// NewStats returns an empty Stats.
+func NewStats() *Stats {
+	return &Stats{
+		addrs:  make(map[string]*addressStats),
+		labels: make(map[string]*labelStats),
+	}
+}
+
+// Handler wraps h, counting every message it handles. Arrival times are taken
+// from the message's Meta if available.
+func (s *Stats) Handler(h Handler) ContextHandler {
+	return ContextHandlerFunc(func(ctx context.Context, msg *osc.Message) error {
+		now := time.Now()
+		if meta, ok := MetaFromContext(ctx); ok && !meta.Received.IsZero() {
+			now = meta.Received
+		}
+		s.record(msg.Pattern, now)
+		return handle(ctx, h, msg)
+	})
+}
+
+// Labelled wraps h, counting the messages it handles, the errors it returns
+// and how long it takes under label, so traffic can be broken down by
+// functional area (such as "lighting" or "audio") rather than by address. Any
+// number of handlers may share a label, for example
+//
+//	l.Handle("/fx/reverb/level", s.Labelled("audio", h))
+func (s *Stats) Labelled(label string, h Handler) ContextHandler {
+	return ContextHandlerFunc(func(ctx context.Context, msg *osc.Message) error {
+		start := time.Now()
+		err := handle(ctx, h, msg)
+		took := time.Since(start)
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		l, ok := s.labels[label]
+		if !ok {
+			l = &labelStats{LabelStats: LabelStats{Label: label}}
+			s.labels[label] = l
+		}
+		l.Matched++
+		if err != nil {
+			l.Errors++
+		}
+		l.total += took
+		l.MaxLatency = max(l.MaxLatency, took)
+		return err
+	})
+}
+
+func (s *Stats) record(addr string, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a := s.address(addr)
+	a.Received++
+	if !a.last.IsZero() {
+		d := now.Sub(a.last).Seconds()
+		if a.Received == 2 {
+			a.interval = d
+		}
+		dev := d - a.interval
+		if dev < 0 {
+			dev = -dev
+		}
+		a.interval += (d - a.interval) * smoothing
+		a.jitter += (dev - a.jitter) * smoothing
+	}
+	a.last = now
+}
+
+// Gap records lost messages, it can be passed directly to Sequenced. The loss
+// is attributed to the address of the message that revealed the gap, so
+// sequences should be per address.
+func (s *Stats) Gap(g Gap) {
+	if g.Got <= g.Want {
+		// Reordered or restarted, nothing lost.
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.address(g.Address).Lost += uint64(g.Got - g.Want)
+}
+
+// address returns the stats for addr, creating them if needed. s.mu must be
+// held.
+func (s *Stats) address(addr string) *addressStats {
+	a, ok := s.addrs[addr]
+	if !ok {
+		if len(s.addrs) >= maxAddresses {
+			oldest := ""
+			for k, b := range s.addrs {
+				if oldest == "" || b.last.Before(s.addrs[oldest].last) {
+					oldest = k
+				}
+			}
+			delete(s.addrs, oldest)
+		}
+		a = &addressStats{AddressStats: AddressStats{Address: addr}}
+		s.addrs[addr] = a
+	}
+	return a
+}
+
+// Stats returns the current statistics for every address seen, sorted by
+// address.
+func (s *Stats) Stats() []AddressStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]AddressStats, 0, len(s.addrs))
+	for _, a := range s.addrs {
+		as := a.AddressStats
+		if a.interval > 0 {
+			as.Rate = 1 / a.interval
+		}
+		as.Jitter = time.Duration(a.jitter * float64(time.Second))
+		out = append(out, as)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Address < out[j].Address })
+	return out
+}
+
+// Labels returns the current statistics for every label used, sorted by label.
+func (s *Stats) Labels() []LabelStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]LabelStats, 0, len(s.labels))
+	for _, l := range s.labels {
+		ls := l.LabelStats
+		ls.Latency = l.total / time.Duration(l.Matched)
+		out = append(out, ls)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Label < out[j].Label })
+	return out
+}
+
+// Report returns the current statistics as OSC messages, so they can be sent
+// on to monitoring tools. Each address gets messages under the prefix
+// followed by the address: ".../received" and ".../lost" (int32, stopping at
+// math.MaxInt32), ".../rate" (messages per second) and ".../jitter" (seconds)
+// as float32.
+func (s *Stats) Report(prefix string) []*osc.Message {
+	var msgs []*osc.Message
+	for _, a := range s.Stats() {
+		rate, jitter := osc.Float32(a.Rate), osc.Float32(a.Jitter.Seconds())
+		base := prefix + a.Address
+		msgs = append(msgs,
+			&osc.Message{Pattern: base + "/received", Arguments: []osc.Argument{counter(a.Received)}},
+			&osc.Message{Pattern: base + "/lost", Arguments: []osc.Argument{counter(a.Lost)}},
+			&osc.Message{Pattern: base + "/rate", Arguments: []osc.Argument{&rate}},
+			&osc.Message{Pattern: base + "/jitter", Arguments: []osc.Argument{&jitter}},
+		)
+	}
+	return msgs
+}
+
+// ReportLabels is like Report, but for the statistics of each label. A label
+// gets messages under the prefix followed by "/" and the label:
+// ".../matched" and ".../errors" (int32, stopping at math.MaxInt32),
+// ".../latency" and ".../maxlatency" (seconds) as float32.
+func (s *Stats) ReportLabels(prefix string) []*osc.Message {
+	var msgs []*osc.Message
+	for _, l := range s.Labels() {
+		latency, maxLatency := osc.Float32(l.Latency.Seconds()), osc.Float32(l.MaxLatency.Seconds())
+		base := prefix + "/" + l.Label
+		msgs = append(msgs,
+			&osc.Message{Pattern: base + "/matched", Arguments: []osc.Argument{counter(l.Matched)}},
+			&osc.Message{Pattern: base + "/errors", Arguments: []osc.Argument{counter(l.Errors)}},
+			&osc.Message{Pattern: base + "/latency", Arguments: []osc.Argument{&latency}},
+			&osc.Message{Pattern: base + "/maxlatency", Arguments: []osc.Argument{&maxLatency}},
+		)
+	}
+	return msgs
+}
+
+// counter returns n as an Int32 for reporting, saturating rather than
+// wrapping around to a negative count.
+func counter(n uint64) *osc.Int32 {
+	return osc.AsInt32(min(n, math.MaxInt32))
+}
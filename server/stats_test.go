@@ -0,0 +1,131 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/pfcm/osc"
+)
+
+func TestStats(t *testing.T) {
+	s := NewStats()
+	h := s.Handler(Sequenced(HandlerFunc(func(*osc.Message) error { return nil }), true, s.Gap))
+	seq := osc.NewSequencer(true)
+
+	start := time.Now()
+	for i := range 100 {
+		msg := &osc.Message{Pattern: "/a"}
+		seq.Stamp(msg)
+		if i%10 == 5 {
+			// Lose every tenth message.
+			continue
+		}
+		// Alternate between 5ms and 15ms apart, with 5ms jitter. With
+		// the losses, the rate is about 90/s.
+		at := start.Add(time.Duration(i)*10*time.Millisecond + time.Duration(i%2)*5*time.Millisecond)
+		ctx := WithMeta(context.Background(), Meta{Received: at})
+		if err := h.HandleContext(ctx, msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stats := s.Stats()
+	if len(stats) != 1 {
+		t.Fatalf("got stats for %d addresses, want 1: %v", len(stats), stats)
+	}
+	a := stats[0]
+	if a.Address != "/a" || a.Received != 90 || a.Lost != 10 {
+		t.Errorf("got %+v, want 90 received and 10 lost for /a", a)
+	}
+	if got := a.Loss(); got != 0.1 {
+		t.Errorf("Loss() = %f, want 0.1", got)
+	}
+	if math.Abs(a.Rate-90) > 15 {
+		t.Errorf("Rate = %f, want ~90", a.Rate)
+	}
+	if a.Jitter < 2*time.Millisecond || a.Jitter > 10*time.Millisecond {
+		t.Errorf("Jitter = %v, want ~5ms", a.Jitter)
+	}
+	if got := len(s.Report("/stats")); got != 4 {
+		t.Errorf("Report returned %d messages, want 4", got)
+	}
+	if got := s.Report("/stats")[0].Pattern; got != "/stats/a/received" {
+		t.Errorf("Report address = %q, want %q", got, "/stats/a/received")
+	}
+}
+
+func TestStatsLimits(t *testing.T) {
+	s := NewStats()
+	start := time.Now()
+	for i := range 2 * maxAddresses {
+		s.record(fmt.Sprintf("/a/%d", i), start.Add(time.Duration(i)))
+	}
+	stats := s.Stats()
+	if len(stats) != maxAddresses {
+		t.Errorf("%d addresses seen, stats for %d, want: %d", 2*maxAddresses, len(stats), maxAddresses)
+	}
+	if _, ok := s.addrs[fmt.Sprint("/a/", 2*maxAddresses-1)]; !ok {
+		t.Error("latest address forgotten, want the oldest forgotten")
+	}
+
+	// Two gaps add up to more than an int32.
+	s.Gap(Gap{Address: "/big", Want: 0, Got: math.MaxInt32})
+	s.Gap(Gap{Address: "/big", Want: 0, Got: math.MaxInt32})
+	found := false
+	for _, msg := range s.Report("/stats") {
+		if msg.Pattern == "/stats/big/lost" {
+			found = true
+			if got := *msg.Arguments[0].(*osc.Int32); got != math.MaxInt32 {
+				t.Errorf("reported %v lost, want: %d", got, math.MaxInt32)
+			}
+		}
+	}
+	if !found {
+		t.Error("Report has no /stats/big/lost")
+	}
+}
+
+func TestLabelled(t *testing.T) {
+	s := NewStats()
+	l := NewListener(nil, 1)
+	ok := HandlerFunc(func(*osc.Message) error { return nil })
+	l.Handle("/light/spot", s.Labelled("lighting", ok))
+	l.Handle("/light/dimmer", s.Labelled("lighting", HandlerFunc(func(*osc.Message) error {
+		time.Sleep(5 * time.Millisecond)
+		return errors.New("dimmer offline")
+	})))
+	l.Handle("/fx/reverb", s.Labelled("audio", ok))
+	for _, addr := range []string{"/light/*", "/light/spot", "/fx/reverb", "/other"} {
+		l.handle(context.Background(), packet{msg: &osc.Message{Pattern: addr}})
+	}
+
+	got := s.Labels()
+	if len(got) != 2 {
+		t.Fatalf("got stats for %d labels, want 2: %v", len(got), got)
+	}
+	if a := got[0]; a.Label != "audio" || a.Matched != 1 || a.Errors != 0 {
+		t.Errorf("got %+v, want 1 matched and no errors for audio", a)
+	}
+	light := got[1]
+	if light.Label != "lighting" || light.Matched != 3 || light.Errors != 1 {
+		t.Errorf("got %+v, want 3 matched and 1 error for lighting", light)
+	}
+	if light.MaxLatency < 5*time.Millisecond || light.Latency > light.MaxLatency {
+		t.Errorf("got latency %v, max %v, want max at least 5ms", light.Latency, light.MaxLatency)
+	}
+	if got := s.ReportLabels("/stats/labels")[4].Pattern; got != "/stats/labels/lighting/matched" {
+		t.Errorf("ReportLabels address = %q, want %q", got, "/stats/labels/lighting/matched")
+	}
+	if len(s.Stats()) != 0 {
+		t.Errorf("Labelled recorded address stats: %v", s.Stats())
+	}
+
+	s.labels["lighting"].Matched = math.MaxUint32 + 1
+	if got := *s.ReportLabels("/stats/labels")[4].Arguments[0].(*osc.Int32); got != math.MaxInt32 {
+		t.Errorf("reported %v matched, want: %d", got, math.MaxInt32)
+	}
+}
@@ -0,0 +1,73 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/pfcm/osc"
+)
+
+// ServeText accepts connections on ln, such as a TCP listener, and reads a
+// message per line in the format of osc.ParseText, dispatching them to the
+// Listener's handlers alongside those from Serve. It's so people can control
+// a program by typing into netcat during development. Each line is answered
+// with "ok" or "error: " and the reason. Handlers' replies with SendTo go over
+// the Listener's connection, so won't reach text clients.
+//
+// It runs until the context is done or accepting fails, and closes ln and
+// every connection before returning.
+func (l *Listener) ServeText(ctx context.Context, ln net.Listener) error {
+	ctx, cancel := context.WithCancel(ctx)
+	context.AfterFunc(ctx, func() { ln.Close() })
+	var wg sync.WaitGroup
+	// Connections only close once the context is done, so cancel it before
+	// waiting for them, however accepting stopped.
+	defer func() {
+		cancel()
+		ln.Close()
+		wg.Wait()
+	}()
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			stop := context.AfterFunc(ctx, func() { conn.Close() })
+			defer stop()
+			defer conn.Close()
+			if err := l.serveTextConn(ctx, conn); err != nil && ctx.Err() == nil {
+				log.Printf("Error serving text connection from %v: %v", conn.RemoteAddr(), err)
+			}
+		}()
+	}
+}
+
+func (l *Listener) serveTextConn(ctx context.Context, conn net.Conn) error {
+	s := bufio.NewScanner(conn)
+	for s.Scan() {
+		received := time.Now()
+		msg, err := osc.ParseText(s.Text())
+		if err == nil {
+			err = l.handle(ctx, packet{msg, Meta{Addr: conn.RemoteAddr(), Received: received}})
+		}
+		reply := "ok\n"
+		if err != nil {
+			reply = fmt.Sprintf("error: %v\n", err)
+		}
+		if _, err := conn.Write([]byte(reply)); err != nil {
+			return err
+		}
+	}
+	return s.Err()
+}
@@ -0,0 +1,123 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pfcm/osc"
+)
+
+func TestServeText(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := NewListener(nil, 1)
+	got := make(chan *osc.Message, 1)
+	l.Handle("/fader", HandlerFunc(func(msg *osc.Message) error {
+		got <- msg
+		return nil
+	}))
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- l.ServeText(ctx, ln) }()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for _, c := range []struct {
+		line, reply string
+	}{
+		{"/fader 0.5", "ok"},
+		{"fader", "error: "},
+		{"/mixer/[1", "error: "},
+	} {
+		if _, err := conn.Write([]byte(c.line + "\n")); err != nil {
+			t.Fatal(err)
+		}
+		reply, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(reply, c.reply) {
+			t.Errorf("reply to %q = %q, want prefix: %q", c.line, reply, c.reply)
+		}
+	}
+	f := osc.Float32(0.5)
+	want := &osc.Message{Pattern: "/fader", Arguments: []osc.Argument{&f}}
+	if msg := <-got; len(osc.Diff(msg, want)) > 0 {
+		t.Errorf("handled %v, want: %v", msg, want)
+	}
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("ServeText() after cancel = %v, want: %v", err, context.Canceled)
+	}
+	// The connection was closed too.
+	if _, err := r.ReadString('\n'); err == nil {
+		t.Error("connection still open after ServeText returned")
+	}
+}
+
+// failingListener accepts one connection from the listener it wraps, then
+// fails.
+type failingListener struct {
+	net.Listener
+	accepted bool
+	once     sync.Once
+	closed   chan struct{}
+}
+
+var errAccept = errors.New("accept failed")
+
+func (f *failingListener) Accept() (net.Conn, error) {
+	if f.accepted {
+		return nil, errAccept
+	}
+	f.accepted = true
+	return f.Listener.Accept()
+}
+
+func (f *failingListener) Close() error {
+	f.once.Do(func() { close(f.closed) })
+	return f.Listener.Close()
+}
+
+func TestServeTextAcceptError(t *testing.T) {
+	inner, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln := &failingListener{Listener: inner, closed: make(chan struct{})}
+	done := make(chan error)
+	go func() { done <- NewListener(nil, 1).ServeText(context.Background(), ln) }()
+
+	// A connected client mustn't keep ServeText waiting once accepting fails.
+	conn, err := net.Dial("tcp", inner.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	select {
+	case err := <-done:
+		if err != errAccept {
+			t.Errorf("ServeText() after Accept failed = %v, want: %v", err, errAccept)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("ServeText() didn't return after Accept failed")
+	}
+	select {
+	case <-ln.closed:
+	default:
+		t.Error("listener not closed after ServeText returned")
+	}
+}
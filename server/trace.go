@@ -0,0 +1,56 @@
+package server
+
+import (
+	"context"
+	"runtime/trace"
+)
+
+// Tracer starts spans around dispatching messages, calling each handler and
+// sending from the Listener's connection, for integrating with tracing systems
+// such as OpenTelemetry. Handlers receive the context returned for their span,
+// so their own spans, and replies sent with SendToContext, nest inside it.
+// Sends made with the osc package directly, such as osc.SendContext or an
+// osc.AsyncSender, aren't traced, as it doesn't depend on this one.
+type Tracer interface {
+	// Start starts a span called name for a message sent to address,
+	// returning a context carrying the span and a function ending it.
+	Start(ctx context.Context, name, address string) (context.Context, func())
+}
+
+// Span names passed to Tracers.
+const (
+	// DispatchSpan covers matching a message and calling all of its
+	// handlers.
+	DispatchSpan = "osc.dispatch"
+	// HandlerSpan covers a single handler. Its address is the one the
+	// handler was registered with.
+	HandlerSpan = "osc.handler"
+	// SendSpan covers encoding and sending a message with
+	// Listener.SendToContext, or SendTo.
+	SendSpan = "osc.send"
+)
+
+// RuntimeTracer is a Tracer recording a runtime/trace task for each span, with
+// the address logged, so messages show up in execution traces.
+type RuntimeTracer struct{}
+
+func (RuntimeTracer) Start(ctx context.Context, name, address string) (context.Context, func()) {
+	ctx, task := trace.NewTask(ctx, name)
+	trace.Log(ctx, "address", address)
+	return ctx, task.End
+}
+
+// WithTracer traces messages with t.
+func WithTracer(t Tracer) Option {
+	return func(l *Listener) {
+		l.tracer = t
+	}
+}
+
+// startSpan starts a span with l.tracer, if there is one.
+func (l *Listener) startSpan(ctx context.Context, name, address string) (context.Context, func()) {
+	if l.tracer == nil {
+		return ctx, func() {}
+	}
+	return l.tracer.Start(ctx, name, address)
+}
@@ -0,0 +1,75 @@
+package server
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/pfcm/osc"
+)
+
+type spanKey struct{}
+
+// recordingTracer records the spans started, and the span each is nested in.
+type recordingTracer struct {
+	spans []string
+}
+
+func (r *recordingTracer) Start(ctx context.Context, name, address string) (context.Context, func()) {
+	parent, _ := ctx.Value(spanKey{}).(string)
+	span := name + " " + address
+	r.spans = append(r.spans, parent+" > "+span)
+	return context.WithValue(ctx, spanKey{}, span), func() {}
+}
+
+func TestTracer(t *testing.T) {
+	r := new(recordingTracer)
+	l := NewListener(nil, 1, WithTracer(r))
+	var inside string
+	for _, p := range []string{"/a/1", "/a/2", "/b"} {
+		l.Handle(p, ContextHandlerFunc(func(ctx context.Context, _ *osc.Message) error {
+			inside, _ = ctx.Value(spanKey{}).(string)
+			return nil
+		}))
+	}
+	l.handle(context.Background(), packet{msg: &osc.Message{Pattern: "/a/*"}})
+	want := []string{
+		" > osc.dispatch /a/*",
+		"osc.dispatch /a/* > osc.handler /a/1",
+		"osc.dispatch /a/* > osc.handler /a/2",
+	}
+	if !slices.Equal(r.spans, want) {
+		t.Errorf("spans = %q, want: %q", r.spans, want)
+	}
+	if inside != "osc.handler /a/2" {
+		t.Errorf("handler ran in span %q, want its own", inside)
+	}
+
+	// Replies are traced inside the handler's span.
+	r.spans = nil
+	l = NewListener(listen(t), 1, WithTracer(r))
+	l.Handle("/ping", l.Replying(func(context.Context, *osc.Message) ([]osc.Argument, error) {
+		return nil, nil
+	}))
+	l.handle(context.Background(), packet{msg: &osc.Message{Pattern: "/ping"}, meta: Meta{Addr: l.conn.LocalAddr()}})
+	want = []string{
+		" > osc.dispatch /ping",
+		"osc.dispatch /ping > osc.handler /ping",
+		"osc.handler /ping > osc.send /reply/ping",
+	}
+	if !slices.Equal(r.spans, want) {
+		t.Errorf("spans = %q, want: %q", r.spans, want)
+	}
+
+	// The runtime tracer shouldn't get in the way when not tracing.
+	l = NewListener(nil, 1, WithTracer(RuntimeTracer{}))
+	called := false
+	l.Handle("/b", HandlerFunc(func(*osc.Message) error {
+		called = true
+		return nil
+	}))
+	l.handle(context.Background(), packet{msg: &osc.Message{Pattern: "/b"}})
+	if !called {
+		t.Error("handler not called with RuntimeTracer")
+	}
+}
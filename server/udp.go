@@ -0,0 +1,31 @@
+package server
+
+import (
+	"context"
+	"net"
+)
+
+// DefaultReadBuffer is the socket receive buffer size ListenUDP asks for, big
+// enough to absorb bursts from controllers sending many messages at once. The
+// OS may cap it, for example at net.core.rmem_max on Linux.
+const DefaultReadBuffer = 1 << 20
+
+// ListenUDP opens a UDP socket on addr and returns a Listener using it. An addr
+// with an empty or unspecified host, such as ":9000", listens on both IPv4
+// and IPv6 where the OS supports it; use "0.0.0.0:9000" for IPv4 only. The
+// read buffer defaults to DefaultReadBuffer, which WithReadBuffer overrides.
+// The context only covers opening the socket.
+func ListenUDP(ctx context.Context, addr string, workers int, opts ...Option) (*Listener, error) {
+	var lc net.ListenConfig
+	conn, err := lc.ListenPacket(ctx, "udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	opts = append([]Option{WithReadBuffer(DefaultReadBuffer)}, opts...)
+	return NewListener(conn, workers, opts...), nil
+}
+
+// LocalAddr returns the address the Listener is receiving on.
+func (l *Listener) LocalAddr() net.Addr {
+	return l.conn.LocalAddr()
+}
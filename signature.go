@@ -0,0 +1,187 @@
+package osc
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Signature is the fixed list of argument types expected by an address, such
+// as "ifs" for an int32, a float32 and a string.
+type Signature struct {
+	tags string
+}
+
+// ParseSignature parses type tags (without the leading ',') into a Signature.
+func ParseSignature(typeTags string) (Signature, error) {
+	for _, t := range typeTags {
+		if _, ok := registeredTypes()[t]; !ok {
+			return Signature{}, fmt.Errorf("signature %q: %w", typeTags, ErrBadTypeTag{Tag: t})
+		}
+	}
+	return Signature{tags: typeTags}, nil
+}
+
+// MustParseSignature is like ParseSignature, but panics if the type tags are
+// invalid. It's for signatures declared in package variables.
+func MustParseSignature(typeTags string) Signature {
+	s, err := ParseSignature(typeTags)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// ParseTypeTags parses a type tag string as it appears in a message, with the
+// leading ',', into a Signature. The ',' is optional, as in ParseSignature.
+func ParseTypeTags(typeTags string) (Signature, error) {
+	return ParseSignature(strings.TrimPrefix(typeTags, ","))
+}
+
+// BuildTypeTags returns the Signature of arguments, without needing a
+// Message. The types needn't be registered, so it works for arguments parsed
+// by any Codec. It's an error for an argument to be nil.
+func BuildTypeTags(args []Argument) (Signature, error) {
+	tags := make([]rune, len(args))
+	for i, a := range args {
+		if a == nil {
+			return Signature{}, fmt.Errorf("argument %d is nil", i)
+		}
+		tags[i] = a.TypeTag()
+	}
+	return Signature{tags: string(tags)}, nil
+}
+
+// String returns the signature's type tags.
+func (s Signature) String() string {
+	return s.tags
+}
+
+// Len returns the number of arguments in the signature.
+func (s Signature) Len() int {
+	return utf8.RuneCountInString(s.tags)
+}
+
+// Tags returns the signature's type tags, one per argument.
+func (s Signature) Tags() []rune {
+	return []rune(s.tags)
+}
+
+// Validate checks the message's arguments match the signature.
+func (s Signature) Validate(msg *Message) error {
+	tags := []rune(s.tags)
+	if len(msg.Arguments) != len(tags) {
+		return fmt.Errorf("expect %d arguments (%q), got: %d (%q)", len(tags), s.tags, len(msg.Arguments), msg.TypeTag())
+	}
+	for i, a := range msg.Arguments {
+		if a.TypeTag() != tags[i] {
+			return fmt.Errorf("expect argument %d of type %q, got: %q", i, tags[i], a.TypeTag())
+		}
+	}
+	return nil
+}
+
+// Build converts Go values to arguments matching the signature, one per type
+// tag. Arguments are used as they are, if they have the right type. Otherwise
+// integers are accepted for 'i' and 'h', floats for 'f' and 'd', strings for
+// 's' and 'S', runes and bytes for 'c', byte slices for 'b', times for 't',
+// the matching bool for 'T' and 'F', and nil for the types without data.
+func (s Signature) Build(values ...any) ([]Argument, error) {
+	tags := []rune(s.tags)
+	if len(values) != len(tags) {
+		return nil, fmt.Errorf("expect %d values (%q), got: %d", len(tags), s.tags, len(values))
+	}
+	args := make([]Argument, len(values))
+	for i, v := range values {
+		a, err := build(tags[i], v)
+		if err != nil {
+			return nil, fmt.Errorf("value %d: %w", i, err)
+		}
+		args[i] = a
+	}
+	return args, nil
+}
+
+// build converts a Go value to an argument of type t.
+func build(t rune, v any) (Argument, error) {
+	if a, ok := v.(Argument); ok && a.TypeTag() == t {
+		return a, nil
+	}
+	switch t {
+	case 'i':
+		switch v := v.(type) {
+		case int:
+			return AsInt32(v), nil
+		case int32:
+			return AsInt32(v), nil
+		case int64:
+			return AsInt32(v), nil
+		}
+	case 'h':
+		switch v := v.(type) {
+		case int:
+			return AsInt64(v), nil
+		case int32:
+			return AsInt64(v), nil
+		case int64:
+			return AsInt64(v), nil
+		}
+	case 'f':
+		switch v := v.(type) {
+		case float32:
+			f := Float32(v)
+			return &f, nil
+		case float64:
+			f := Float32(v)
+			return &f, nil
+		}
+	case 'd':
+		switch v := v.(type) {
+		case float32:
+			f := Float64(v)
+			return &f, nil
+		case float64:
+			f := Float64(v)
+			return &f, nil
+		}
+	case 's':
+		if v, ok := v.(string); ok {
+			return AsString(v), nil
+		}
+	case 'S':
+		if v, ok := v.(string); ok {
+			sym := Symbol(v)
+			return &sym, nil
+		}
+	case 'c':
+		switch v := v.(type) {
+		case rune:
+			c := Char(v)
+			return &c, nil
+		case byte:
+			c := Char(v)
+			return &c, nil
+		}
+	case 'b':
+		if v, ok := v.([]byte); ok {
+			return (*Blob)(&v), nil
+		}
+	case 't':
+		if v, ok := v.(time.Time); ok {
+			return &TimeTag{v}, nil
+		}
+	case 'T', 'F':
+		if b, ok := v.(bool); ok && AsBool(b).TypeTag() == t {
+			return AsBool(b), nil
+		}
+		if v == nil {
+			return registeredTypes()[t](), nil
+		}
+	case 'N', 'I':
+		if v == nil {
+			return registeredTypes()[t](), nil
+		}
+	}
+	return nil, fmt.Errorf("expect value for type %q, got: %T", t, v)
+}
@@ -0,0 +1,107 @@
+package osc
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSignature(t *testing.T) {
+	if _, err := ParseSignature("ix"); !errors.Is(err, ErrBadTypeTag{Tag: 'x'}) {
+		t.Errorf(`ParseSignature("ix") = %v, want: ErrBadTypeTag{'x'}`, err)
+	}
+	s := MustParseSignature("ifsbtN")
+	now := time.Unix(1700000000, 0)
+	args, err := s.Build(3, 0.5, "name", []byte{1, 2}, now, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := &Message{Pattern: "/sig", Arguments: args}
+	if got := msg.TypeTag(); got != s.String() {
+		t.Errorf("Build() type tags = %q, want: %q", got, s)
+	}
+	if err := s.Validate(msg); err != nil {
+		t.Errorf("Validate(built message) = %v, want: nil", err)
+	}
+	if got := int32(*args[0].(*Int32)); got != 3 {
+		t.Errorf("Build() int32 = %d, want: 3", got)
+	}
+	if got := args[4].(*TimeTag).Time; !got.Equal(now) {
+		t.Errorf("Build() time tag = %v, want: %v", got, now)
+	}
+
+	for _, c := range []struct {
+		name string
+		args []Argument
+	}{
+		{"too few", args[:5]},
+		{"wrong type", []Argument{AsString("3"), args[1], args[2], args[3], args[4], args[5]}},
+	} {
+		if err := s.Validate(&Message{Pattern: "/sig", Arguments: c.args}); err == nil {
+			t.Errorf("Validate(%s) = nil, want error", c.name)
+		}
+	}
+
+	for _, values := range [][]any{
+		{3, 0.5, "name", []byte{}, now},
+		{"3", 0.5, "name", []byte{}, now, nil},
+		{3, 0.5, "name", []byte{}, now, 1},
+	} {
+		if _, err := s.Build(values...); err == nil {
+			t.Errorf("Build(%v) = nil error, want error", values)
+		}
+	}
+
+	args, err = MustParseSignature("TF").Build(true, false)
+	if err != nil || args[0] != Argument(True{}) || args[1] != Argument(False{}) {
+		t.Errorf("Build(true, false) = %v, %v, want: [True False], nil", args, err)
+	}
+	if _, err := MustParseSignature("T").Build(false); err == nil {
+		t.Error(`Signature("T").Build(false) = nil error, want error`)
+	}
+
+	// Arguments of the right type pass straight through.
+	i := AsInt32(7)
+	args, err = MustParseSignature("i").Build(i)
+	if err != nil || args[0] != Argument(i) {
+		t.Errorf("Build(*Int32) = %v, %v, want: %v, nil", args, err, i)
+	}
+
+	// Optional types.
+	args, err = MustParseSignature("hdSc").Build(int64(1)<<40, 0.25, "bang", 'x')
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := (&Message{Pattern: "/sig", Arguments: args}).TypeTag(); got != "hdSc" {
+		t.Errorf("Build() optional type tags = %q, want: %q", got, "hdSc")
+	}
+	if got := *args[3].(*Char); got != 'x' {
+		t.Errorf("Build() char = %v, want: %v", got, Char('x'))
+	}
+}
+
+func TestTypeTags(t *testing.T) {
+	for _, tt := range []string{",ifs", "ifs"} {
+		s, err := ParseTypeTags(tt)
+		if err != nil || s.String() != "ifs" || s.Len() != 3 {
+			t.Errorf("ParseTypeTags(%q) = %v (%d tags), %v, want: ifs (3 tags), nil", tt, s, s.Len(), err)
+		}
+	}
+	if _, err := ParseTypeTags(",i?"); !errors.Is(err, ErrBadTypeTag{Tag: '?'}) {
+		t.Errorf(`ParseTypeTags(",i?") = %v, want: ErrBadTypeTag{'?'}`, err)
+	}
+
+	s, err := BuildTypeTags([]Argument{AsInt32(1), AsString("x"), True{}, &vendorInt{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := s.Tags(), []rune("isTv"); string(got) != string(want) {
+		t.Errorf("BuildTypeTags().Tags() = %q, want: %q", got, want)
+	}
+	if _, err := BuildTypeTags([]Argument{AsInt32(1), nil}); err == nil {
+		t.Error("BuildTypeTags with a nil argument = nil error, want error")
+	}
+	if s, err := BuildTypeTags(nil); err != nil || s.Len() != 0 {
+		t.Errorf("BuildTypeTags(nil) = %q, %v, want: \"\", nil", s, err)
+	}
+}
@@ -0,0 +1,137 @@
+package osc
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseText parses a message written as a line of text, for typing by hand:
+// the address followed by the arguments, separated by spaces, such as
+//
+//	/mixer/ch/1/fader 0.75
+//	/ch/1/name "Kick drum" true
+//
+// Integers are Int32s and other numbers Float32s. true, false, nil and
+// impulse are True, False, Null and Impulse. Anything else is a String, which
+// must be quoted, with Go's escapes, if it contains spaces or would otherwise
+// be read as another type.
+func ParseText(line string) (*Message, error) {
+	fields, err := splitText(line)
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		return nil, ErrEmptyPacket
+	}
+	if !strings.HasPrefix(fields[0], "/") {
+		return nil, fmt.Errorf("%w: expect address starting with /, got: %q", ErrBadAddress, fields[0])
+	}
+	msg := &Message{Pattern: fields[0]}
+	for _, f := range fields[1:] {
+		msg.Arguments = append(msg.Arguments, textArgument(f))
+	}
+	return msg, nil
+}
+
+// splitText splits a line on spaces, keeping quoted strings together and
+// unquoting them. Quoted strings are marked by keeping a leading '"'.
+func splitText(line string) ([]string, error) {
+	var fields []string
+	for {
+		line = strings.TrimLeft(line, " \t\r\n")
+		if line == "" {
+			return fields, nil
+		}
+		if line[0] != '"' {
+			end := strings.IndexAny(line, " \t\r\n")
+			if end < 0 {
+				end = len(line)
+			}
+			fields = append(fields, line[:end])
+			line = line[end:]
+			continue
+		}
+		quoted, err := strconv.QuotedPrefix(line)
+		if err != nil {
+			return nil, fmt.Errorf("expect quoted string, got: %s", line)
+		}
+		s, err := strconv.Unquote(quoted)
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, `"`+s)
+		line = line[len(quoted):]
+	}
+}
+
+// textArgument converts a field from splitText to an argument.
+func textArgument(f string) Argument {
+	if s, ok := strings.CutPrefix(f, `"`); ok {
+		return AsString(s)
+	}
+	switch f {
+	case "true":
+		return True{}
+	case "false":
+		return False{}
+	case "nil":
+		return Null{}
+	case "impulse":
+		return Impulse{}
+	}
+	if i, err := strconv.ParseInt(f, 0, 32); err == nil {
+		return AsInt32(i)
+	}
+	if v, err := strconv.ParseFloat(f, 32); err == nil {
+		f := Float32(v)
+		return &f
+	}
+	return AsString(f)
+}
+
+// FormatText writes a message as a line of text that ParseText reads back as
+// the same message, without the trailing newline. Only the argument types
+// ParseText produces can be written, see FormatTextArgument.
+func FormatText(m *Message) (string, error) {
+	var b strings.Builder
+	b.WriteString(m.Pattern)
+	for i, a := range m.Arguments {
+		f, err := FormatTextArgument(a)
+		if err != nil {
+			return "", fmt.Errorf("argument %d: %w", i, err)
+		}
+		b.WriteByte(' ')
+		b.WriteString(f)
+	}
+	return b.String(), nil
+}
+
+// FormatTextArgument writes a single argument as it appears in the text
+// format: Int32s in decimal, Float32s always with a decimal point or exponent,
+// Strings quoted with Go's escapes, and True, False, Null and Impulse as words.
+// Other types are an error.
+func FormatTextArgument(a Argument) (string, error) {
+	switch a := a.(type) {
+	case *Int32:
+		return strconv.FormatInt(int64(*a), 10), nil
+	case *Float32:
+		f := strconv.FormatFloat(float64(*a), 'g', -1, 32)
+		if !strings.ContainsAny(f, ".eInN") {
+			// Otherwise it would be read as an Int32.
+			f += ".0"
+		}
+		return f, nil
+	case *String:
+		return strconv.Quote(string(*a)), nil
+	case True:
+		return "true", nil
+	case False:
+		return "false", nil
+	case Null:
+		return "nil", nil
+	case Impulse:
+		return "impulse", nil
+	}
+	return "", fmt.Errorf("type %c can't be written as text", a.TypeTag())
+}
@@ -0,0 +1,85 @@
+package osc
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestParseText(t *testing.T) {
+	f := Float32(0.75)
+	for _, c := range []struct {
+		line string
+		want *Message
+	}{
+		{"/go", &Message{Pattern: "/go"}},
+		{"  /mixer/ch/1/fader 0.75\n", &Message{Pattern: "/mixer/ch/1/fader", Arguments: []Argument{&f}}},
+		{`/ch/1/name "Kick drum" true -3 0x10`, &Message{Pattern: "/ch/1/name", Arguments: []Argument{
+			AsString("Kick drum"), True{}, AsInt32(-3), AsInt32(16),
+		}}},
+		{`/a false nil impulse word "true" "say \"hi\""`, &Message{Pattern: "/a", Arguments: []Argument{
+			False{}, Null{}, Impulse{}, AsString("word"), AsString("true"), AsString(`say "hi"`),
+		}}},
+	} {
+		got, err := ParseText(c.line)
+		if err != nil {
+			t.Errorf("ParseText(%q): %v", c.line, err)
+			continue
+		}
+		if d := Diff(got, c.want); len(d) > 0 {
+			t.Errorf("ParseText(%q) = %v, want: %v (%v)", c.line, got, c.want, d)
+		}
+	}
+
+	for _, c := range []struct {
+		line string
+		want error
+	}{
+		{"", ErrEmptyPacket},
+		{"  ", ErrEmptyPacket},
+		{"go 1", ErrBadAddress},
+	} {
+		if _, err := ParseText(c.line); !errors.Is(err, c.want) {
+			t.Errorf("ParseText(%q) = %v, want: %v", c.line, err, c.want)
+		}
+	}
+	if _, err := ParseText(`/a "unterminated`); err == nil {
+		t.Error("ParseText with unterminated string = nil error, want error")
+	}
+}
+
+func TestFormatText(t *testing.T) {
+	f, negZero, whole := Float32(0.75), Float32(math.Copysign(0, -1)), Float32(2)
+	for _, c := range []struct {
+		msg  *Message
+		want string
+	}{
+		{&Message{Pattern: "/go"}, "/go"},
+		{&Message{Pattern: "/ch/1/name", Arguments: []Argument{
+			AsString("Kick drum"), True{}, AsInt32(-3), &f, &whole, &negZero,
+		}}, `/ch/1/name "Kick drum" true -3 0.75 2.0 -0.0`},
+		{&Message{Pattern: "/a", Arguments: []Argument{
+			False{}, Null{}, Impulse{}, AsString("true"), AsString(`say "hi"`),
+		}}, `/a false nil impulse "true" "say \"hi\""`},
+	} {
+		got, err := FormatText(c.msg)
+		if err != nil {
+			t.Errorf("FormatText(%v): %v", c.msg, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("FormatText(%v) = %q, want: %q", c.msg, got, c.want)
+		}
+		back, err := ParseText(got)
+		if err != nil {
+			t.Errorf("ParseText(%q): %v", got, err)
+			continue
+		}
+		if d := Diff(back, c.msg); len(d) > 0 {
+			t.Errorf("ParseText(FormatText(%v)) = %v (%v)", c.msg, back, d)
+		}
+	}
+	if _, err := FormatText(&Message{Pattern: "/b", Arguments: []Argument{AsInt64(1)}}); err == nil {
+		t.Error("FormatText with an Int64 = nil error, want error")
+	}
+}
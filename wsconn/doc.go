@@ -0,0 +1,9 @@
+// package wsconn provides a net.PacketConn for Go programs running in a
+// browser (GOOS=js, GOARCH=wasm), which carries each OSC packet in a binary
+// WebSocket message to and from a gateway. Because it's a net.PacketConn it
+// can be used with osc.Send and server.Listener just like a UDP socket.
+//
+// Browsers can't send UDP, so the gateway on the other end of the WebSocket is
+// responsible for forwarding packets on to their destination. The address
+// passed to WriteTo is ignored.
+package wsconn
@@ -0,0 +1,180 @@
+//go:build js && wasm
+
+package wsconn
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"sync"
+	"syscall/js"
+	"time"
+)
+
+// Addr is the address of a WebSocket, its URL.
+type Addr string
+
+func (Addr) Network() string  { return "websocket" }
+func (a Addr) String() string { return string(a) }
+
+// Conn is a net.PacketConn over a WebSocket.
+type Conn struct {
+	ws        js.Value
+	addr      Addr
+	listeners []listener
+
+	// recv holds received packets. If it's full, further packets are
+	// dropped, as they would be by a UDP socket.
+	recv   chan []byte
+	closed chan struct{}
+	once   sync.Once
+
+	mu           sync.Mutex
+	readDeadline time.Time
+	// deadlineChanged is closed when the read deadline changes, to wake up
+	// any ReadFrom in progress.
+	deadlineChanged chan struct{}
+}
+
+var _ net.PacketConn = (*Conn)(nil)
+
+type listener struct {
+	event string
+	fn    js.Func
+}
+
+// recvQueue is the number of received packets buffered by a Conn.
+const recvQueue = 100
+
+// Dial connects to the WebSocket at url, waiting until the connection is open
+// or the context is done.
+func Dial(ctx context.Context, url string) (*Conn, error) {
+	c := &Conn{
+		ws:     js.Global().Get("WebSocket").New(url),
+		addr:   Addr(url),
+		recv:   make(chan []byte, recvQueue),
+		closed: make(chan struct{}),
+
+		deadlineChanged: make(chan struct{}),
+	}
+	c.ws.Set("binaryType", "arraybuffer")
+
+	// Callbacks run on the JavaScript event loop, so they must not block.
+	opened := make(chan struct{}, 1)
+	c.on("open", func(js.Value) {
+		select {
+		case opened <- struct{}{}:
+		default:
+		}
+	})
+	c.on("message", func(ev js.Value) {
+		data := ev.Get("data")
+		if data.Type() == js.TypeString {
+			// Not an OSC packet.
+			return
+		}
+		arr := js.Global().Get("Uint8Array").New(data)
+		b := make([]byte, arr.Get("length").Int())
+		js.CopyBytesToGo(b, arr)
+		select {
+		case c.recv <- b:
+		default:
+		}
+	})
+	c.on("close", func(js.Value) {
+		c.once.Do(func() { close(c.closed) })
+	})
+
+	select {
+	case <-opened:
+		return c, nil
+	case <-c.closed:
+		c.Close()
+		return nil, errors.New("websocket closed while connecting")
+	case <-ctx.Done():
+		c.Close()
+		return nil, ctx.Err()
+	}
+}
+
+// on registers a handler for a WebSocket event.
+func (c *Conn) on(event string, f func(js.Value)) {
+	fn := js.FuncOf(func(this js.Value, args []js.Value) any {
+		f(args[0])
+		return nil
+	})
+	c.listeners = append(c.listeners, listener{event, fn})
+	c.ws.Call("addEventListener", event, fn)
+}
+
+// ReadFrom reads the next packet received on the WebSocket. The address is
+// always the WebSocket's.
+func (c *Conn) ReadFrom(b []byte) (int, net.Addr, error) {
+	for {
+		c.mu.Lock()
+		deadline, changed := c.readDeadline, c.deadlineChanged
+		c.mu.Unlock()
+		var timeout <-chan time.Time
+		if !deadline.IsZero() {
+			timeout = time.After(time.Until(deadline))
+		}
+		select {
+		case p := <-c.recv:
+			return copy(b, p), c.addr, nil
+		case <-c.closed:
+			return 0, nil, net.ErrClosed
+		case <-timeout:
+			return 0, nil, os.ErrDeadlineExceeded
+		case <-changed:
+		}
+	}
+}
+
+// WriteTo sends a packet over the WebSocket. The address is ignored, it's up
+// to the gateway to route the packet.
+func (c *Conn) WriteTo(b []byte, _ net.Addr) (int, error) {
+	select {
+	case <-c.closed:
+		return 0, net.ErrClosed
+	default:
+	}
+	arr := js.Global().Get("Uint8Array").New(len(b))
+	js.CopyBytesToJS(arr, b)
+	c.ws.Call("send", arr)
+	return len(b), nil
+}
+
+// Close closes the WebSocket.
+func (c *Conn) Close() error {
+	c.ws.Call("close")
+	c.once.Do(func() { close(c.closed) })
+	for _, l := range c.listeners {
+		c.ws.Call("removeEventListener", l.event, l.fn)
+		l.fn.Release()
+	}
+	c.listeners = nil
+	return nil
+}
+
+func (c *Conn) LocalAddr() net.Addr { return c.addr }
+
+func (c *Conn) SetDeadline(t time.Time) error {
+	return c.SetReadDeadline(t)
+}
+
+// SetReadDeadline sets the deadline for calls to ReadFrom, including any
+// currently blocked.
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.readDeadline = t
+	close(c.deadlineChanged)
+	c.deadlineChanged = make(chan struct{})
+	return nil
+}
+
+// SetWriteDeadline does nothing, writes to a WebSocket never block.
+func (c *Conn) SetWriteDeadline(time.Time) error {
+	return nil
+}